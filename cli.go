@@ -30,10 +30,12 @@ var initCmd = &cobra.Command{
 	},
 }
 
+var commitAmend bool
+
 var commitCmd = &cobra.Command{
-	Use:   "commit <message>",
+	Use:   "commit <message> | commit --amend [<message>]",
 	Short: "Save current state with a commit message",
-	Args:  cobra.ExactArgs(1), // Expect exactly one argument (the commit message)
+	Args:  cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
 
 		cwd, err := os.Getwd()
@@ -41,6 +43,21 @@ var commitCmd = &cobra.Command{
 			fmt.Println("Failed to get current directory:", err)
 			return
 		}
+
+		if commitAmend {
+			// Without a new message the amended commit keeps the old one.
+			commitMessage := ""
+			if len(args) == 1 {
+				commitMessage = args[0]
+			}
+			vcs.HandleCommitAmend(commitMessage, cwd)
+			return
+		}
+
+		if len(args) != 1 {
+			fmt.Println("Error: expected a commit message")
+			return
+		}
 		commitMessage := args[0]
 		fmt.Println("Commit message:", commitMessage)
 		vcs.HandleCommit(commitMessage, cwd)
@@ -55,24 +72,199 @@ var logCmd = &cobra.Command{
 	},
 }
 
+var showCmd = &cobra.Command{
+	Use:   "show [<object>...]",
+	Short: "Show commits with their diff, trees, blobs and tags",
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleShow(args)
+	},
+}
+
+var (
+	catFileType   bool
+	catFileSize   bool
+	catFilePretty bool
+	catFileExists bool
+)
+
+var catFileCmd = &cobra.Command{
+	Use:   "cat-file (-t | -s | -p | -e) <object>",
+	Short: "Print the type, size or content of an object, or check that it exists",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		flags := 0
+		for _, set := range []bool{catFileType, catFileSize, catFilePretty, catFileExists} {
+			if set {
+				flags++
+			}
+		}
+		if flags != 1 {
+			fmt.Println("Error: expected exactly one of -t, -s, -p and -e")
+			return
+		}
+
+		switch {
+		case catFileType:
+			vcs.HandleCatFile("t", args[0])
+		case catFileSize:
+			vcs.HandleCatFile("s", args[0])
+		case catFilePretty:
+			vcs.HandleCatFile("p", args[0])
+		case catFileExists:
+			// Only the exit status answers.
+			if !vcs.CatFileExists(args[0]) {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+var (
+	lsTreeRecursive bool
+	lsTreeTrees     bool
+	lsTreeNameOnly  bool
+)
+
+var lsTreeCmd = &cobra.Command{
+	Use:   "ls-tree [-r] [-d] [--name-only] <tree-ish> [<path>...]",
+	Short: "List the entries of a tree",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		opts := vcs.LsTreeOptions{
+			Recursive: lsTreeRecursive,
+			TreesOnly: lsTreeTrees,
+			Paths:     args[1:],
+		}
+		vcs.HandleLsTree(args[0], opts, lsTreeNameOnly)
+	},
+}
+
+var (
+	lsFilesStage    bool
+	lsFilesModified bool
+	lsFilesOthers   bool
+	lsFilesIgnored  bool
+)
+
+var lsFilesCmd = &cobra.Command{
+	Use:   "ls-files [--stage] [--modified] [--others] [--ignored] [<path>...]",
+	Short: "List the files in the index, or changed and untracked files in the working tree",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		opts := vcs.LsFilesOptions{
+			Modified: lsFilesModified,
+			Others:   lsFilesOthers,
+			Ignored:  lsFilesIgnored,
+			Paths:    args,
+		}
+		vcs.HandleLsFiles(opts, lsFilesStage)
+	},
+}
+
+var (
+	hashObjectWrite bool
+	hashObjectStdin bool
+)
+
+var hashObjectCmd = &cobra.Command{
+	Use:   "hash-object [-w] [--stdin] [<file>...]",
+	Short: "Print the blob hash of files or standard input",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if !hashObjectStdin && len(args) == 0 {
+			exitOnError(fmt.Errorf("expected files or --stdin"))
+		}
+		exitOnError(vcs.HandleHashObject(args, hashObjectStdin, hashObjectWrite))
+	},
+}
+
+var writeTreeCmd = &cobra.Command{
+	Use:   "write-tree",
+	Short: "Store the index as a tree and print its hash",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(vcs.HandleWriteTree())
+	},
+}
+
+var (
+	commitTreeParent  string
+	commitTreeMessage string
+)
+
+var commitTreeCmd = &cobra.Command{
+	Use:   "commit-tree <tree> [-p <parent>] [-m <message>]",
+	Short: "Store a commit of a tree without moving any refs and print its hash",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(vcs.HandleCommitTree(args[0], commitTreeParent, commitTreeMessage, cmd.Flags().Changed("message")))
+	},
+}
+
+var updateRefReason string
+
+var updateRefCmd = &cobra.Command{
+	Use:   "update-ref [-m <reason>] <ref> <new> [<old>]",
+	Short: "Point a ref at an object, only if it still holds <old> when given",
+	Args:  cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		oldRev := ""
+		if len(args) == 3 {
+			oldRev = args[2]
+		}
+		exitOnError(vcs.HandleUpdateRef(args[0], args[1], oldRev, updateRefReason))
+	},
+}
+
+// exitOnError reports err on standard error and exits with status 1, so
+// scripts driving the plumbing commands can tell that they failed.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
 var catCmd = &cobra.Command{
-	Use:   "cat <hash>",
+	Use:   "cat <rev>",
 	Short: "Read object",
 	Args:  cobra.ExactArgs(1), // Expect exactly one argument (the commit message)
 	Run: func(cmd *cobra.Command, args []string) {
 
-		hash := args[0]
+		rev := args[0]
 
-		vcs.HandleCat(hash)
+		vcs.HandleCat(rev)
 	},
 }
 
 var checkoutCmd = &cobra.Command{
-	Use:   "checkout <hash>",
-	Short: "Checkout",
-	Args:  cobra.ExactArgs(1), // Expect exactly one argument (the commit message)
+	Use:   "checkout <rev> | checkout [<rev>] -- <path>...",
+	Short: "Checkout a commit or branch, or restore paths from it",
+	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 
+		// "checkout [<rev>] -- <path>..." restores single paths: from the
+		// index, or from <rev> into both the index and the working tree.
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			if dash > 1 || dash == len(args) {
+				fmt.Println("Error: expected [<rev>] -- <path>...")
+				return
+			}
+			if dash == 1 {
+				vcs.HandleRestore(args[0], args[1:], true, true)
+			} else {
+				vcs.HandleRestore("", args, false, true)
+			}
+			return
+		}
+		if len(args) != 1 {
+			fmt.Println("Error: expected a single revision")
+			return
+		}
+
 		// Should check for uncommited changes here.
 		// if True. user should commit or stash
 		cwd, err := os.Getwd()
@@ -80,8 +272,657 @@ var checkoutCmd = &cobra.Command{
 			fmt.Println("Failed to get current directory:", err)
 			return
 		}
-		hash := args[0]
-		vcs.HandleCheckout(cwd, hash)
+		rev := args[0]
+		vcs.HandleCheckout(cwd, rev)
+	},
+}
+
+var (
+	tagAnnotate bool
+	tagMessage  string
+	tagDelete   bool
+	tagForce    bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [<name> [<rev>]]",
+	Short: "Create, list or delete tags",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if len(args) == 0 {
+			vcs.HandleTagList()
+			return
+		}
+
+		name := args[0]
+		if tagDelete {
+			vcs.HandleTagDelete(name)
+			return
+		}
+
+		rev := ""
+		if len(args) == 2 {
+			rev = args[1]
+		}
+		// A message always makes an annotated tag, like git does.
+		annotate := tagAnnotate || cmd.Flags().Changed("message")
+		if annotate && !cmd.Flags().Changed("message") {
+			fmt.Println("Error: annotated tags need a message (-m)")
+			return
+		}
+		vcs.HandleTag(name, rev, annotate, tagMessage, tagForce)
+	},
+}
+
+var (
+	branchDelete bool
+	branchForce  bool
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch [<name> [<rev>]]",
+	Short: "Create, list or delete branches",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if len(args) == 0 {
+			vcs.HandleBranchList()
+			return
+		}
+
+		name := args[0]
+		if branchDelete {
+			vcs.HandleBranchDelete(name)
+			return
+		}
+
+		rev := ""
+		if len(args) == 2 {
+			rev = args[1]
+		}
+		vcs.HandleBranch(name, rev, branchForce)
+	},
+}
+
+var reflogCmd = &cobra.Command{
+	Use:   "reflog [<ref>]",
+	Short: "Show where HEAD or a branch has pointed",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		vcs.HandleReflog(name)
+	},
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <path>...",
+	Short: "Stage files in the index",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleAdd(args)
+	},
+}
+
+var (
+	resetSoft  bool
+	resetMixed bool
+	resetHard  bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset [--soft | --mixed | --hard] [<rev>] [-- <path>...]",
+	Short: "Move the current branch or unstage paths",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		revArgs, paths := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			revArgs, paths = args[:dash], args[dash:]
+		}
+		if len(revArgs) > 1 {
+			fmt.Println("Error: expected at most one revision before --")
+			return
+		}
+
+		rev := ""
+		if len(revArgs) == 1 {
+			rev = revArgs[0]
+		}
+
+		mode := vcs.ResetMixed
+		if resetSoft {
+			mode = vcs.ResetSoft
+		} else if resetHard {
+			mode = vcs.ResetHard
+		}
+		vcs.HandleReset(mode, rev, paths)
+	},
+}
+
+var (
+	restoreSource   string
+	restoreStaged   bool
+	restoreWorktree bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [--source <rev>] [--staged] [--worktree] <path>...",
+	Short: "Restore files in the working tree or index",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleRestore(restoreSource, args, restoreStaged, restoreWorktree)
+	},
+}
+
+var (
+	revertNoCommit bool
+	revertContinue bool
+	revertAbort    bool
+)
+
+var revertCmd = &cobra.Command{
+	Use:   "revert <rev>... | revert --continue | revert --abort",
+	Short: "Record commits that undo earlier commits",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if !revertContinue && !revertAbort && len(args) == 0 {
+			fmt.Println("Error: expected at least one commit to revert")
+			return
+		}
+		vcs.HandleRevert(args, revertNoCommit, revertContinue, revertAbort)
+	},
+}
+
+var (
+	cherryPickContinue bool
+	cherryPickSkip     bool
+	cherryPickAbort    bool
+)
+
+var cherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick <rev>... | cherry-pick --continue | --skip | --abort",
+	Short: "Apply the changes of existing commits on top of HEAD",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if !cherryPickContinue && !cherryPickSkip && !cherryPickAbort && len(args) == 0 {
+			fmt.Println("Error: expected at least one commit to cherry-pick")
+			return
+		}
+		vcs.HandleCherryPick(args, cherryPickContinue, cherryPickSkip, cherryPickAbort)
+	},
+}
+
+var (
+	rebaseOnto      string
+	rebaseTodo      string
+	rebasePrintTodo bool
+	rebaseContinue  bool
+	rebaseSkip      bool
+	rebaseAbort     bool
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase [--onto <newbase>] [--todo <file>] <upstream> [<branch>] | rebase --continue | --skip | --abort",
+	Short: "Replay commits on top of another base",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if rebaseContinue || rebaseSkip || rebaseAbort {
+			vcs.HandleRebaseControl(rebaseContinue, rebaseSkip, rebaseAbort)
+			return
+		}
+		if len(args) == 0 {
+			fmt.Println("Error: expected an upstream to rebase onto")
+			return
+		}
+
+		if rebasePrintTodo {
+			vcs.HandleRebaseTodo(args[0])
+			return
+		}
+
+		branch := ""
+		if len(args) == 2 {
+			branch = args[1]
+		}
+		vcs.HandleRebase(args[0], rebaseOnto, branch, rebaseTodo)
+	},
+}
+
+var (
+	filterDrop       []string
+	filterRename     []string
+	filterAuthor     []string
+	filterMessage    []string
+	filterPruneEmpty bool
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Rewrite every commit to drop or rename paths, authors or message text",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleFilter(filterDrop, filterRename, filterAuthor, filterMessage, filterPruneEmpty)
+	},
+}
+
+var remoteVerbose bool
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote [-v]",
+	Short: "List the configured remotes",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleRemoteList(remoteVerbose)
+	},
+}
+
+var remoteAddCmd = &cobra.Command{
+	Use:   "add <name> <path-or-url>",
+	Short: "Add a remote pointing at another repository",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleRemoteAdd(args[0], args[1])
+	},
+}
+
+var remoteRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a remote and its remote-tracking branches",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleRemoteRemove(args[0])
+	},
+}
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch [<remote>]",
+	Short: "Download objects and refs from a remote",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remote := ""
+		if len(args) == 1 {
+			remote = args[0]
+		}
+		vcs.HandleFetch(remote)
+	},
+}
+
+var (
+	pushForce       bool
+	pushSetUpstream bool
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push [<remote> [<branch>]]",
+	Short: "Upload a branch and its objects to a remote",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, branch := "", ""
+		if len(args) > 0 {
+			remote = args[0]
+		}
+		if len(args) > 1 {
+			branch = args[1]
+		}
+		vcs.HandlePush(remote, branch, pushForce, pushSetUpstream)
+	},
+}
+
+var (
+	cloneDepth  int
+	cloneFilter string
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [--depth <n>] [--filter=blob:none] <path-url-or-bundle> [<directory>]",
+	Short: "Copy a repository into a new directory",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := ""
+		if len(args) == 2 {
+			dir = args[1]
+		}
+		vcs.HandleClone(args[0], dir, cloneDepth, cloneFilter)
+	},
+}
+
+var serveAddr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the repository over HTTP for clone, fetch and push",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleServe(serveAddr)
+	},
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Move refs and objects through a single file",
+}
+
+var bundleAll bool
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <file> [--all] [<rev>...]",
+	Short: "Write refs and everything they need to a file; ^<rev> or <a>..<b> leaves out history the receiver has",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		revs := args[1:]
+		if bundleAll {
+			revs = append(revs, "--all")
+		}
+		if len(revs) == 0 {
+			fmt.Println("Error: expected the refs to bundle, or --all")
+			return
+		}
+		vcs.HandleBundleCreate(args[0], revs)
+	},
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Check that a bundle is intact and can be applied to this repository",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBundleVerify(args[0])
+	},
+}
+
+var bundleUnbundleCmd = &cobra.Command{
+	Use:   "unbundle <file>",
+	Short: "Store the objects of a bundle and print its refs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBundleUnbundle(args[0])
+	},
+}
+
+var exportGitCmd = &cobra.Command{
+	Use:   "export-git <dir>",
+	Short: "Write branches, tags and their objects into a Git repository",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleExportGit(args[0])
+	},
+}
+
+var importGitCmd = &cobra.Command{
+	Use:   "import-git <dir>",
+	Short: "Convert the branches and tags of a Git repository into this one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleImportGit(args[0])
+	},
+}
+
+var fastExportCmd = &cobra.Command{
+	Use:   "fast-export [<ref>...]",
+	Short: "Write history as a fast-import stream to standard output",
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleFastExport(args)
+	},
+}
+
+var fastImportCmd = &cobra.Command{
+	Use:   "fast-import",
+	Short: "Read a fast-import stream from standard input",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleFastImport()
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show staged, unstaged and untracked changes",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleStatus()
+	},
+}
+
+var (
+	blameLines     string
+	blamePorcelain bool
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame [-L <start>,<end>] [--porcelain] <path> [<rev>]",
+	Short: "Show the commit that last changed each line of a file",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		rev := ""
+		if len(args) == 2 {
+			rev = args[1]
+		}
+		vcs.HandleBlame(args[0], rev, blameLines, blamePorcelain)
+	},
+}
+
+var (
+	grepLineNumbers bool
+	grepIgnoreCase  bool
+	grepFilesOnly   bool
+	grepCount       bool
+	grepWord        bool
+	grepCached      bool
+	grepWorktree    bool
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep [-n] [-i] [-l] [-c] [-w] [--cached | --worktree] <regex> [<rev>] [-- <path>...]",
+	Short: "Search the files of a revision, the index or the working tree",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		patternArgs, paths := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			patternArgs, paths = args[:dash], args[dash:]
+		}
+		if len(patternArgs) == 0 || len(patternArgs) > 2 {
+			fmt.Println("Error: expected <regex> [<rev>] before --")
+			return
+		}
+
+		rev := ""
+		if len(patternArgs) == 2 {
+			rev = patternArgs[1]
+		}
+
+		opts := vcs.GrepOptions{
+			IgnoreCase: grepIgnoreCase,
+			WordRegexp: grepWord,
+			Cached:     grepCached,
+			Worktree:   grepWorktree,
+			Paths:      paths,
+		}
+		vcs.HandleGrep(patternArgs[0], rev, opts, grepLineNumbers, grepFilesOnly, grepCount)
+	},
+}
+
+var bisectCmd = &cobra.Command{
+	Use:   "bisect",
+	Short: "Binary search the history for the commit that introduced a bug",
+}
+
+var bisectStartCmd = &cobra.Command{
+	Use:   "start [<bad> [<good>...]]",
+	Short: "Start bisecting, optionally marking a bad and good commits",
+	Run: func(cmd *cobra.Command, args []string) {
+		bad := ""
+		if len(args) > 0 {
+			bad = args[0]
+			args = args[1:]
+		}
+		vcs.HandleBisectStart(bad, args)
+	},
+}
+
+var bisectGoodCmd = &cobra.Command{
+	Use:   "good [<rev>...]",
+	Short: "Mark commits, HEAD by default, as good",
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectMark("good", args)
+	},
+}
+
+var bisectBadCmd = &cobra.Command{
+	Use:   "bad [<rev>]",
+	Short: "Mark a commit, HEAD by default, as bad",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectMark("bad", args)
+	},
+}
+
+var bisectSkipCmd = &cobra.Command{
+	Use:   "skip [<rev>...]",
+	Short: "Mark commits, HEAD by default, as untestable",
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectMark("skip", args)
+	},
+}
+
+var bisectResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Stop bisecting and go back to where it started",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectReset()
+	},
+}
+
+var bisectLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the commits marked so far",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectLog()
+	},
+}
+
+var bisectRunCmd = &cobra.Command{
+	Use:   "run <cmd> [<arg>...]",
+	Short: "Bisect automatically, marking each commit by the exit code of <cmd>",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleBisectRun(args)
+	},
+}
+
+var sparseCheckoutCmd = &cobra.Command{
+	Use:   "sparse-checkout",
+	Short: "Limit the working tree to some directories or patterns",
+}
+
+var sparseNoCone bool
+
+var sparseCheckoutSetCmd = &cobra.Command{
+	Use:   "set [--no-cone] <dir-or-pattern>...",
+	Short: "Check out only the given directories, or the files matching the patterns with --no-cone",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSparseCheckoutSet(args, !sparseNoCone)
+	},
+}
+
+var sparseCheckoutAddCmd = &cobra.Command{
+	Use:   "add <dir-or-pattern>...",
+	Short: "Add directories or patterns to the sparse checkout",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSparseCheckoutAdd(args)
+	},
+}
+
+var sparseCheckoutListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the sparse checkout directories or patterns",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSparseCheckoutList()
+	},
+}
+
+var sparseCheckoutDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Check out every file again",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSparseCheckoutDisable()
+	},
+}
+
+var worktreeCmd = &cobra.Command{
+	Use:   "worktree",
+	Short: "Manage extra working directories that share this repository",
+}
+
+var worktreeAddCmd = &cobra.Command{
+	Use:   "add <path> <branch-or-rev>",
+	Short: "Create a worktree at <path> with a branch, or a detached commit, checked out",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleWorktreeAdd(args[0], args[1])
+	},
+}
+
+var worktreeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the main worktree and the linked ones",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleWorktreeList()
+	},
+}
+
+var worktreeForce bool
+
+var worktreeRemoveCmd = &cobra.Command{
+	Use:   "remove [--force] <path>",
+	Short: "Delete a linked worktree",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleWorktreeRemove(args[0], worktreeForce)
+	},
+}
+
+var submoduleCmd = &cobra.Command{
+	Use:   "submodule",
+	Short: "Manage repositories nested in the working tree",
+}
+
+var submoduleAddCmd = &cobra.Command{
+	Use:   "add <url> <path>",
+	Short: "Clone a repository into <path> and record it in .gtmodules",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSubmoduleAdd(args[0], args[1])
+	},
+}
+
+var submoduleUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Clone missing submodules and check out their recorded commits",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSubmoduleUpdate()
+	},
+}
+
+var submoduleStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the commit checked out in each submodule",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vcs.HandleSubmoduleStatus()
 	},
 }
 
@@ -117,7 +958,160 @@ func init() {
 	rootCmd.AddCommand(commitCmd)
 	rootCmd.AddCommand(catCmd)
 	rootCmd.AddCommand(checkoutCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(branchCmd)
+	rootCmd.AddCommand(reflogCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(revertCmd)
+	rootCmd.AddCommand(cherryPickCmd)
+	rootCmd.AddCommand(rebaseCmd)
+	rootCmd.AddCommand(filterCmd)
+	rootCmd.AddCommand(remoteCmd)
+	rootCmd.AddCommand(fetchCmd)
+	rootCmd.AddCommand(pushCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(exportGitCmd)
+	rootCmd.AddCommand(importGitCmd)
+	rootCmd.AddCommand(fastExportCmd)
+	rootCmd.AddCommand(fastImportCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(sparseCheckoutCmd)
+	rootCmd.AddCommand(worktreeCmd)
+	rootCmd.AddCommand(submoduleCmd)
+	rootCmd.AddCommand(blameCmd)
+	rootCmd.AddCommand(bisectCmd)
+	rootCmd.AddCommand(grepCmd)
+	rootCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(catFileCmd)
+	rootCmd.AddCommand(lsTreeCmd)
+	rootCmd.AddCommand(lsFilesCmd)
+	rootCmd.AddCommand(hashObjectCmd)
+	rootCmd.AddCommand(writeTreeCmd)
+	rootCmd.AddCommand(commitTreeCmd)
+	rootCmd.AddCommand(updateRefCmd)
 	rootCmd.AddCommand(stashCmd)
 	rootCmd.AddCommand(stashApplyCmd)
 	rootCmd.AddCommand(testCmd)
+
+	commitCmd.Flags().BoolVar(&commitAmend, "amend", false, "Replace the last commit instead of adding a new one")
+
+	tagCmd.Flags().BoolVarP(&tagAnnotate, "annotate", "a", false, "Create an annotated tag object")
+	tagCmd.Flags().StringVarP(&tagMessage, "message", "m", "", "Tag message")
+	tagCmd.Flags().BoolVarP(&tagDelete, "delete", "d", false, "Delete the tag")
+	tagCmd.Flags().BoolVarP(&tagForce, "force", "f", false, "Replace an existing tag")
+
+	branchCmd.Flags().BoolVarP(&branchDelete, "delete", "d", false, "Delete the branch")
+	branchCmd.Flags().BoolVarP(&branchForce, "force", "f", false, "Reset an existing branch")
+
+	resetCmd.Flags().BoolVar(&resetSoft, "soft", false, "Only move the branch")
+	resetCmd.Flags().BoolVar(&resetMixed, "mixed", false, "Move the branch and reset the index (default)")
+	resetCmd.Flags().BoolVar(&resetHard, "hard", false, "Move the branch and reset the index and working tree")
+	resetCmd.MarkFlagsMutuallyExclusive("soft", "mixed", "hard")
+
+	restoreCmd.Flags().StringVarP(&restoreSource, "source", "s", "", "Restore from this revision instead of the index")
+	restoreCmd.Flags().BoolVarP(&restoreStaged, "staged", "S", false, "Restore the index")
+	restoreCmd.Flags().BoolVarP(&restoreWorktree, "worktree", "W", false, "Restore the working tree (default)")
+
+	revertCmd.Flags().BoolVarP(&revertNoCommit, "no-commit", "n", false, "Apply the inverse changes without committing")
+	revertCmd.Flags().BoolVar(&revertContinue, "continue", false, "Continue after resolving conflicts")
+	revertCmd.Flags().BoolVar(&revertAbort, "abort", false, "Cancel the revert and restore the original state")
+	revertCmd.MarkFlagsMutuallyExclusive("continue", "abort")
+
+	cherryPickCmd.Flags().BoolVar(&cherryPickContinue, "continue", false, "Continue after resolving conflicts")
+	cherryPickCmd.Flags().BoolVar(&cherryPickSkip, "skip", false, "Skip the commit that stopped and continue")
+	cherryPickCmd.Flags().BoolVar(&cherryPickAbort, "abort", false, "Cancel the cherry-pick and restore the original state")
+	cherryPickCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort")
+
+	rebaseCmd.Flags().StringVar(&rebaseOnto, "onto", "", "Replay onto this commit instead of upstream")
+	rebaseCmd.Flags().StringVar(&rebaseTodo, "todo", "", "Read the steps (pick, reword, squash, fixup, drop, exec) from a file")
+	rebaseCmd.Flags().BoolVar(&rebasePrintTodo, "print-todo", false, "Print the default todo list instead of rebasing")
+	rebaseCmd.Flags().BoolVar(&rebaseContinue, "continue", false, "Continue after resolving conflicts")
+	rebaseCmd.Flags().BoolVar(&rebaseSkip, "skip", false, "Skip the commit that stopped and continue")
+	rebaseCmd.Flags().BoolVar(&rebaseAbort, "abort", false, "Cancel the rebase and return to the original branch")
+	rebaseCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort")
+
+	filterCmd.Flags().StringArrayVar(&filterDrop, "drop", nil, "Remove paths matching this pattern from every commit")
+	filterCmd.Flags().StringArrayVar(&filterRename, "rename", nil, "Rename a path or directory, as old=new")
+	filterCmd.Flags().StringArrayVar(&filterAuthor, "author", nil, "Rewrite an author, as old=new where old is \"Name <email>\" or just the email")
+	filterCmd.Flags().StringArrayVar(&filterMessage, "replace-message", nil, "Replace text in commit messages, as old=new")
+	filterCmd.Flags().BoolVar(&filterPruneEmpty, "prune-empty", false, "Drop commits that become empty")
+
+	remoteCmd.Flags().BoolVarP(&remoteVerbose, "verbose", "v", false, "Show the remote paths")
+	remoteCmd.AddCommand(remoteAddCmd)
+	remoteCmd.AddCommand(remoteRemoveCmd)
+
+	pushCmd.Flags().BoolVarP(&pushForce, "force", "f", false, "Update the remote branch even if it is not a fast-forward")
+	pushCmd.Flags().BoolVarP(&pushSetUpstream, "set-upstream", "u", false, "Make the branch track the remote branch")
+
+	cloneCmd.Flags().IntVar(&cloneDepth, "depth", 0, "Only fetch the last <n> commits of each branch")
+	cloneCmd.Flags().StringVar(&cloneFilter, "filter", "", "Leave out objects and fetch them when needed; only blob:none is supported")
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8418", "Address to listen on")
+
+	bundleCreateCmd.Flags().BoolVar(&bundleAll, "all", false, "Bundle every branch and tag")
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleVerifyCmd)
+	bundleCmd.AddCommand(bundleUnbundleCmd)
+
+	sparseCheckoutSetCmd.Flags().BoolVar(&sparseNoCone, "no-cone", false, "Treat the arguments as gitignore style patterns instead of directories")
+	sparseCheckoutCmd.AddCommand(sparseCheckoutSetCmd)
+	sparseCheckoutCmd.AddCommand(sparseCheckoutAddCmd)
+	sparseCheckoutCmd.AddCommand(sparseCheckoutListCmd)
+	sparseCheckoutCmd.AddCommand(sparseCheckoutDisableCmd)
+
+	worktreeRemoveCmd.Flags().BoolVarP(&worktreeForce, "force", "f", false, "Remove the worktree even if it has uncommitted changes")
+	worktreeCmd.AddCommand(worktreeAddCmd)
+	worktreeCmd.AddCommand(worktreeListCmd)
+	worktreeCmd.AddCommand(worktreeRemoveCmd)
+
+	submoduleCmd.AddCommand(submoduleAddCmd)
+	submoduleCmd.AddCommand(submoduleUpdateCmd)
+	submoduleCmd.AddCommand(submoduleStatusCmd)
+
+	blameCmd.Flags().StringVarP(&blameLines, "lines", "L", "", "Only blame lines <start>,<end>; <end> may be +<count>")
+	blameCmd.Flags().BoolVar(&blamePorcelain, "porcelain", false, "Print a machine readable format")
+
+	grepCmd.Flags().BoolVarP(&grepLineNumbers, "line-number", "n", false, "Prefix matching lines with their line number")
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "Ignore case differences")
+	grepCmd.Flags().BoolVarP(&grepFilesOnly, "files-with-matches", "l", false, "Only print the names of matching files")
+	grepCmd.Flags().BoolVarP(&grepCount, "count", "c", false, "Print the number of matching lines per file")
+	grepCmd.Flags().BoolVarP(&grepWord, "word-regexp", "w", false, "Only match whole words")
+	grepCmd.Flags().BoolVar(&grepCached, "cached", false, "Search the index instead of a revision")
+	grepCmd.Flags().BoolVar(&grepWorktree, "worktree", false, "Search the tracked files in the working tree instead of a revision")
+
+	catFileCmd.Flags().BoolVarP(&catFileType, "type", "t", false, "Print the object type")
+	catFileCmd.Flags().BoolVarP(&catFileSize, "size", "s", false, "Print the object size")
+	catFileCmd.Flags().BoolVarP(&catFilePretty, "pretty", "p", false, "Print the object content")
+	catFileCmd.Flags().BoolVarP(&catFileExists, "exists", "e", false, "Exit with status 1 unless the object exists")
+
+	lsTreeCmd.Flags().BoolVarP(&lsTreeRecursive, "recursive", "r", false, "Recurse into subtrees")
+	lsTreeCmd.Flags().BoolVarP(&lsTreeTrees, "trees", "d", false, "Only list trees")
+	lsTreeCmd.Flags().BoolVar(&lsTreeNameOnly, "name-only", false, "Only print paths")
+
+	lsFilesCmd.Flags().BoolVarP(&lsFilesStage, "stage", "s", false, "Print the mode and hash of index entries")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesModified, "modified", "m", false, "List tracked files that are modified or deleted")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesOthers, "others", "o", false, "List untracked files that are not ignored")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesIgnored, "ignored", "i", false, "List only untracked files matched by .gtignore")
+
+	hashObjectCmd.Flags().BoolVarP(&hashObjectWrite, "write", "w", false, "Store the blobs")
+	hashObjectCmd.Flags().BoolVar(&hashObjectStdin, "stdin", false, "Read the content from standard input")
+
+	commitTreeCmd.Flags().StringVarP(&commitTreeParent, "parent", "p", "", "Parent commit")
+	commitTreeCmd.Flags().StringVarP(&commitTreeMessage, "message", "m", "", "Commit message, read from standard input when omitted")
+
+	updateRefCmd.Flags().StringVarP(&updateRefReason, "message", "m", "", "Reason recorded in the reflog")
+
+	// Flags after the command belong to it, not to bisect run.
+	bisectRunCmd.Flags().SetInterspersed(false)
+	bisectCmd.AddCommand(bisectStartCmd)
+	bisectCmd.AddCommand(bisectGoodCmd)
+	bisectCmd.AddCommand(bisectBadCmd)
+	bisectCmd.AddCommand(bisectSkipCmd)
+	bisectCmd.AddCommand(bisectResetCmd)
+	bisectCmd.AddCommand(bisectLogCmd)
+	bisectCmd.AddCommand(bisectRunCmd)
 }
@@ -4,4 +4,22 @@ const (
 	GTDir      = ".gt"
 	ObjectsDir = ".gt/objects"
 	StashDir   = ".gt/objects/stash"
+	RefsDir    = ".gt/refs"
+	TagsDir    = ".gt/refs/tags"
+	HeadsDir   = ".gt/refs/heads"
+	LogsDir    = ".gt/logs"
+	ConfigFile = ".gt/config"
+
+	SequencerDir = ".gt/sequencer"
+	RebaseDir    = ".gt/rebase-merge"
+	BisectDir    = ".gt/bisect"
+	FilterMap    = ".gt/filter-map"
+	GitMap       = ".gt/git-map"
+	SparseFile   = ".gt/sparse-checkout"
+	ShallowFile  = ".gt/shallow"
+	ModulesFile  = ".gtmodules"
+	IgnoreFile   = ".gtignore"
+
+	DefaultBranch = "main"
+	DefaultRemote = "origin"
 )
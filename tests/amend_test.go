@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestCommitAmend(t *testing.T) {
+	tmp := setupRepo(t)
+
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "one"})
+	second := commitFiles(t, tmp, "second", map[string]string{"a.txt": "two"})
+	original, err := vcs.ReadCommit(second)
+	if err != nil {
+		t.Fatalf("reading commit failed: %v", err)
+	}
+
+	writeFile(t, tmp, "b.txt", "new file")
+	vcs.AddToIndex([]string{"b.txt"})
+	vcs.HandleCommitAmend("second, amended", tmp)
+
+	amended, err := vcs.ReadCommit(headHash(t))
+	if err != nil {
+		t.Fatalf("reading amended commit failed: %v", err)
+	}
+	if amended.Hash == second {
+		t.Fatalf("amend did not create a new commit")
+	}
+	if amended.ParentHash != first {
+		t.Fatalf("amended commit should keep the parent %s, got %s", first, amended.ParentHash)
+	}
+	if amended.Message != "second, amended" {
+		t.Fatalf("unexpected message %q", amended.Message)
+	}
+	if amended.Author != original.Author {
+		t.Fatalf("amend should keep the author")
+	}
+	if branch, _ := vcs.ReadRef("refs/heads/main"); branch != amended.Hash {
+		t.Fatalf("branch was not moved to the amended commit")
+	}
+	if _, ok, _ := vcs.FindTreeEntry(amended.TreeHash, "b.txt"); !ok {
+		t.Fatalf("amended commit should include b.txt")
+	}
+
+	// The replaced commit stays reachable through the reflog.
+	previous, err := vcs.ResolveRevision("HEAD@{1}")
+	if err != nil || previous != second {
+		t.Fatalf("HEAD@{1} = %s, %v; want %s", previous, err, second)
+	}
+
+	// Without a message the old one is kept.
+	vcs.HandleCommitAmend("", tmp)
+	again, _ := vcs.ReadCommit(headHash(t))
+	if again.Message != "second, amended" || again.ParentHash != first {
+		t.Fatalf("amend without a message changed the message or parent")
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+// setupHistory commits n.txt holding 1 to count and returns the commits,
+// oldest first.
+func setupHistory(t *testing.T, count int) (string, []string) {
+	t.Helper()
+
+	tmp := setupRepo(t)
+	var commits []string
+	for i := 1; i <= count; i++ {
+		commits = append(commits, commitFiles(t, tmp, fmt.Sprintf("c%d", i), map[string]string{"n.txt": strconv.Itoa(i)}))
+	}
+	return tmp, commits
+}
+
+func TestBisect(t *testing.T) {
+	tmp, commits := setupHistory(t, 8)
+
+	step, err := vcs.StartBisect("", nil)
+	if err != nil || step.Status == "" {
+		t.Fatalf("bisect start without commits should wait, got %+v, %v", step, err)
+	}
+	if step, err = vcs.MarkBisect("bad", nil); err != nil || step.Next != "" {
+		t.Fatalf("one bad commit is not enough to start, got %+v, %v", step, err)
+	}
+
+	// Commit 6 introduced the bug; commit 4 cannot be tested.
+	step, err = vcs.MarkBisect("good", []string{commits[0]})
+	for tested := 0; err == nil && step.FirstBad == "" && tested < 8; tested++ {
+		if headHash(t) != step.Next {
+			t.Fatalf("HEAD should be detached at the commit to test")
+		}
+		n, _ := strconv.Atoi(readFile(t, tmp, "n.txt"))
+		switch {
+		case n == 4:
+			step, err = vcs.MarkBisect("skip", nil)
+		case n >= 6:
+			step, err = vcs.MarkBisect("bad", nil)
+		default:
+			step, err = vcs.MarkBisect("good", nil)
+		}
+	}
+	if err != nil || step.FirstBad != commits[5] {
+		t.Fatalf("expected commit 6 as the first bad commit, got %+v, %v", step, err)
+	}
+
+	log, _ := vcs.BisectLog()
+	if !strings.HasPrefix(log[len(log)-1], "# first bad commit: ["+commits[5]+"]") {
+		t.Fatalf("the log should end with the first bad commit: %v", log)
+	}
+
+	if err := vcs.ResetBisect(); err != nil {
+		t.Fatalf("bisect reset failed: %v", err)
+	}
+	if head, _, _ := vcs.ReadHead(); head != "refs/heads/main" || readFile(t, tmp, "n.txt") != "8" {
+		t.Fatalf("reset should return to main, HEAD is %q", head)
+	}
+	if _, err := vcs.BisectLog(); err == nil {
+		t.Fatalf("bisect state should be gone after reset")
+	}
+}
+
+func TestBisectRun(t *testing.T) {
+	_, commits := setupHistory(t, 10)
+
+	if _, err := vcs.StartBisect("HEAD", []string{commits[0]}); err != nil {
+		t.Fatalf("bisect start failed: %v", err)
+	}
+	// The argument with a space must arrive as one argument.
+	script := `test "$1" = "a b" && test "$(cat n.txt)" -lt 3`
+	step, err := vcs.RunBisect([]string{"sh", "-c", script, "sh", "a b"}, func(vcs.BisectStep) {})
+	if err != nil || step.FirstBad != commits[2] {
+		t.Fatalf("expected commit 3 as the first bad commit, got %+v, %v", step, err)
+	}
+
+	// Only skipped commits between good and bad leave the answer open.
+	vcs.ResetBisect()
+	vcs.StartBisect(commits[3], []string{commits[1]})
+	step, err = vcs.MarkBisect("skip", nil)
+	if err != nil || len(step.Candidates) != 2 {
+		t.Fatalf("expected the bad and the skipped commit as candidates, got %+v, %v", step, err)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestBlame(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{"f.txt": "a\nb\nc\n", "other.txt": "x"})
+	second := commitFiles(t, tmp, "second", map[string]string{"f.txt": "a\nB\nc\nd\n"})
+	commitFiles(t, tmp, "unrelated", map[string]string{"other.txt": "y"})
+	third := commitFiles(t, tmp, "third", map[string]string{"f.txt": "top\na\nB\nd\n"})
+
+	lines, err := vcs.Blame("f.txt", "")
+	if err != nil {
+		t.Fatalf("blame failed: %v", err)
+	}
+
+	want := []struct {
+		commit   string
+		origLine int
+		text     string
+	}{
+		{third, 1, "top"},
+		{first, 1, "a"},
+		{second, 2, "B"},
+		{second, 4, "d"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), lines)
+	}
+	for i, line := range lines {
+		if line.Commit.Hash != want[i].commit || line.OrigLine != want[i].origLine || line.Text != want[i].text || line.Line != i+1 {
+			t.Fatalf("line %d: got %+v, want %+v", i+1, line, want[i])
+		}
+	}
+	if !lines[1].Boundary || lines[0].Boundary {
+		t.Fatalf("only lines from the root commit are boundary lines")
+	}
+
+	// An older revision is blamed as it was.
+	lines, err = vcs.Blame("f.txt", second)
+	if err != nil || len(lines) != 4 || lines[2].Commit.Hash != first || lines[2].OrigLine != 3 {
+		t.Fatalf("unexpected blame at the second commit: %+v, %v", lines, err)
+	}
+
+	if _, err := vcs.Blame("missing.txt", ""); err == nil {
+		t.Fatalf("blaming a missing path should fail")
+	}
+}
+
+func TestParseLineRange(t *testing.T) {
+	cases := []struct {
+		spec       string
+		start, end int
+		ok         bool
+	}{
+		{"2,3", 2, 3, true},
+		{"2,+2", 2, 3, true},
+		{"3,", 3, 10, true},
+		{"8,20", 8, 10, true},
+		{"0,2", 0, 0, false},
+		{"4,2", 0, 0, false},
+		{"11,12", 0, 0, false},
+		{"x", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		start, end, err := vcs.ParseLineRange(c.spec, 10)
+		if (err == nil) != c.ok || start != c.start || end != c.end {
+			t.Errorf("ParseLineRange(%q) = %d, %d, %v", c.spec, start, end, err)
+		}
+	}
+}
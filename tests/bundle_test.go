@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestBundleCloneAndIncrementalFetch(t *testing.T) {
+	origin := setupRepo(t)
+	first := commitFiles(t, origin, "first", map[string]string{"a.txt": "a"})
+	vcs.CreateTag("v1", "HEAD", false, "", false)
+
+	full := filepath.Join(t.TempDir(), "full.bundle")
+	if _, err := vcs.CreateBundle(full, []string{"--all"}); err != nil {
+		t.Fatalf("creating bundle failed: %v", err)
+	}
+	second := commitFiles(t, origin, "second", map[string]string{"b.txt": "b"})
+	incremental := filepath.Join(t.TempDir(), "incremental.bundle")
+	if _, err := vcs.CreateBundle(incremental, []string{"v1..main"}); err != nil {
+		t.Fatalf("creating incremental bundle failed: %v", err)
+	}
+
+	// A fresh repository lacks the commit the incremental bundle builds on.
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if err := vcs.Clone(incremental, "broken", vcs.FetchOptions{}); err == nil {
+		t.Fatalf("cloning an incremental bundle should fail")
+	}
+	if fileExists("broken") {
+		t.Fatalf("a failed clone should not leave its directory behind")
+	}
+
+	if err := vcs.Clone(full, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone from bundle failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if headHash(t) != first || readFile(t, clone, "a.txt") != "a" {
+		t.Fatalf("clone from bundle did not check out main")
+	}
+
+	bundle, err := vcs.VerifyBundle(incremental)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if len(bundle.Prerequisites) != 1 || bundle.Prerequisites[0] != first {
+		t.Fatalf("unexpected prerequisites: %v", bundle.Prerequisites)
+	}
+	if vcs.ObjectExists(second) {
+		t.Fatalf("verify should not store objects")
+	}
+
+	if _, err := vcs.Unbundle(incremental); err != nil {
+		t.Fatalf("unbundle failed: %v", err)
+	}
+	if !vcs.ObjectExists(second) {
+		t.Fatalf("unbundle did not store the new commit")
+	}
+}
+
+func TestBundleRejectsCorruption(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a"})
+
+	file := filepath.Join(t.TempDir(), "repo.bundle")
+	if _, err := vcs.CreateBundle(file, []string{"main"}); err != nil {
+		t.Fatalf("creating bundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(file)
+	data[len(data)-50] ^= 0xff
+	os.WriteFile(file, data, 0644)
+
+	if _, err := vcs.VerifyBundle(file); err == nil {
+		t.Fatalf("a corrupted bundle should not verify")
+	}
+}
+
+func TestBundleRejectsBadRefNames(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "a"})
+
+	file := filepath.Join(t.TempDir(), "evil.bundle")
+	if _, err := vcs.CreateBundle(file, []string{"main"}); err != nil {
+		t.Fatalf("creating bundle failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(file)
+	header := first + " refs/heads/main\n"
+	evil := strings.Replace(string(data), header, header+first+" refs/tags/../../../pwned\n", 1)
+	os.WriteFile(file, []byte(evil), 0644)
+
+	if _, err := vcs.VerifyBundle(file); err == nil {
+		t.Fatalf("a bundle with a bad ref name should not verify")
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if err := vcs.Clone(file, "dst", vcs.FetchOptions{}); err == nil {
+		t.Fatalf("cloning a bundle with a bad ref name should fail")
+	}
+	if fileExists(filepath.Join(dir, "dst", "pwned")) || fileExists(filepath.Join(dir, "pwned")) {
+		t.Fatalf("a bad bundle ref name escaped .gt/refs")
+	}
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"GoTrack/constants"
+	"GoTrack/vcs"
+)
+
+func TestCherryPickKeepsMessageAndAuthor(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "base", map[string]string{"f.txt": "a\nb\nc\nd\ne\n"})
+	if _, err := vcs.CreateBranch("feature", "HEAD", false); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+	vcs.HandleCheckout(tmp, "feature")
+
+	t.Setenv("GT_AUTHOR_NAME", "Original Author")
+	t.Setenv("GT_AUTHOR_EMAIL", "author@example.com")
+	fix := commitFiles(t, tmp, "fix the first line", map[string]string{"f.txt": "A\nb\nc\nd\ne\n"})
+	t.Setenv("GT_AUTHOR_NAME", "Someone Else")
+
+	vcs.HandleCheckout(tmp, "main")
+	main := commitFiles(t, tmp, "main change", map[string]string{"f.txt": "a\nb\nc\nd\nE\n"})
+
+	if err := vcs.StartSequence("cherry-pick", []string{fix}, false); err != nil {
+		t.Fatalf("cherry-pick failed: %v", err)
+	}
+
+	head, _ := vcs.ReadCommit(headHash(t))
+	if head.ParentHash != main || head.Message != "fix the first line" {
+		t.Fatalf("unexpected cherry-picked commit: %+v", head)
+	}
+	if head.Author != "Original Author <author@example.com>" {
+		t.Fatalf("author = %q, want the original author", head.Author)
+	}
+	if got := readFile(t, tmp, "f.txt"); got != "A\nb\nc\nd\nE\n" {
+		t.Fatalf("unexpected content after cherry-pick:\n%s", got)
+	}
+}
+
+func TestCherryPickSkipAndContinue(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "base", map[string]string{"f.txt": "one\n"})
+	vcs.CreateBranch("feature", "HEAD", false)
+	vcs.HandleCheckout(tmp, "feature")
+	conflicting := commitFiles(t, tmp, "conflicting", map[string]string{"f.txt": "feature\n"})
+	clean := commitFiles(t, tmp, "clean", map[string]string{"g.txt": "new\n"})
+	again := commitFiles(t, tmp, "again", map[string]string{"f.txt": "feature again\n"})
+
+	vcs.HandleCheckout(tmp, "main")
+	main := commitFiles(t, tmp, "main", map[string]string{"f.txt": "main\n"})
+
+	if err := vcs.StartSequence("cherry-pick", []string{conflicting, clean, again}, false); err == nil {
+		t.Fatalf("expected the first pick to conflict")
+	}
+
+	// The state survives on disk, as if gt had been restarted.
+	state, err := os.ReadFile(constants.SequencerDir + "/todo")
+	if err != nil || len(state) == 0 {
+		t.Fatalf("todo list was not persisted: %v", err)
+	}
+
+	if err := vcs.SkipSequence("cherry-pick"); err == nil {
+		t.Fatalf("expected the third pick to conflict after skipping")
+	}
+	if readFile(t, tmp, "g.txt") != "new\n" {
+		t.Fatalf("the clean pick should have been applied")
+	}
+
+	writeFile(t, tmp, "f.txt", "resolved\n")
+	if err := vcs.ContinueSequence("cherry-pick"); err != nil {
+		t.Fatalf("continue failed: %v", err)
+	}
+
+	head, _ := vcs.ReadCommit(headHash(t))
+	parent, _ := vcs.ReadCommit(head.ParentHash)
+	if head.Message != "again" || parent.Message != "clean" || parent.ParentHash != main {
+		t.Fatalf("unexpected history after cherry-pick: %q <- %q", parent.Message, head.Message)
+	}
+	if _, err := os.Stat(constants.SequencerDir); !os.IsNotExist(err) {
+		t.Fatalf("cherry-pick state should be removed when done")
+	}
+}
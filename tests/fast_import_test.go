@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestFastExportImportRoundTrip(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n"})
+	if _, err := vcs.CreateTag("v1", "HEAD", true, "release", false); err != nil {
+		t.Fatalf("failed to tag: %v", err)
+	}
+	os.RemoveAll(filepath.Join(tmp, "dir"))
+	second := commitFiles(t, tmp, "second\n\nbody", map[string]string{"c.txt": "c\n"})
+	tag, _ := vcs.ReadRef("refs/tags/v1")
+
+	var stream bytes.Buffer
+	if err := vcs.FastExport(&stream, nil); err != nil {
+		t.Fatalf("fast-export failed: %v", err)
+	}
+	if !strings.Contains(stream.String(), "D dir/b.txt\n") {
+		t.Fatalf("the second commit should delete dir/b.txt:\n%s", stream.String())
+	}
+
+	setupRepo(t)
+	result, err := vcs.FastImport(&stream)
+	if err != nil {
+		t.Fatalf("fast-import failed: %v", err)
+	}
+	if result.Commits != 2 || result.Tags != 1 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	if headHash(t) != second {
+		t.Fatalf("imported main is %s, want %s", headHash(t), second)
+	}
+	if imported, _ := vcs.ReadRef("refs/tags/v1"); imported != tag {
+		t.Fatalf("imported tag is %s, want %s", imported, tag)
+	}
+	if readFile(t, ".", "c.txt") != "c\n" {
+		t.Fatalf("fast-import did not check out the files")
+	}
+}
+
+func TestFastImportStreamCommands(t *testing.T) {
+	setupRepo(t)
+
+	stream := `# written by hand
+blob
+mark :1
+data 6
+hello
+
+commit refs/heads/main
+mark :2
+author A U Thor <author@example.com> 1700000000 +0100
+committer C O Mitter <committer@example.com> 1700000100 +0100
+data <<EOF
+initial
+EOF
+M 100644 :1 docs/readme.txt
+M 100644 inline notes.txt
+data 5
+note
+
+commit refs/heads/main
+committer C O Mitter <committer@example.com> 1700000200 +0100
+data 7
+rename
+from :2
+R docs/readme.txt README
+D notes.txt
+
+commit refs/heads/topic
+committer C O Mitter <committer@example.com> 1700000300 +0100
+data 6
+fresh
+deleteall
+M 100644 :1 only.txt
+
+reset refs/tags/start
+from :2
+
+done
+`
+	result, err := vcs.FastImport(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("fast-import failed: %v", err)
+	}
+	if result.Commits != 3 {
+		t.Fatalf("expected 3 commits, got %+v", result)
+	}
+
+	head, _ := vcs.ReadCommit(headHash(t))
+	if head.Message != "rename" || head.TimeStamp != 1700000200 {
+		t.Fatalf("unexpected head commit: %+v", head)
+	}
+	files, _ := vcs.FlattenCommit(head.Hash)
+	if len(files) != 1 || files["README"].Hash == "" {
+		t.Fatalf("unexpected files after rename: %v", files)
+	}
+
+	root, _ := vcs.ReadCommit(head.ParentHash)
+	if root.Author != "A U Thor <author@example.com>" || root.Message != "initial" {
+		t.Fatalf("unexpected root commit: %+v", root)
+	}
+	if start, _ := vcs.ResolveRevision("start"); start != root.Hash {
+		t.Fatalf("tag start should point at the root commit")
+	}
+
+	topic, _ := vcs.ResolveRevision("topic")
+	topicCommit, _ := vcs.ReadCommit(topic)
+	topicFiles, _ := vcs.FlattenCommit(topic)
+	if topicCommit.ParentHash != "" || len(topicFiles) != 1 || topicFiles["only.txt"].Hash == "" {
+		t.Fatalf("topic should be a new root commit holding only.txt")
+	}
+}
+
+func TestFastImportReportsLosses(t *testing.T) {
+	setupRepo(t)
+	stream := `blob
+mark :1
+data 10
+#!/bin/sh
+
+commit refs/heads/main
+mark :2
+committer C <c@example.com> 1700000000 +0000
+data 5
+first
+M 100755 :1 run.sh
+M 120000 inline link
+data 6
+run.sh
+
+commit refs/heads/topic
+mark :3
+committer C <c@example.com> 1700000100 +0000
+data 5
+topic
+from :2
+
+commit refs/heads/main
+committer C <c@example.com> 1700000200 +0000
+data 5
+merge
+from :2
+merge :3
+
+done
+`
+	result, err := vcs.FastImport(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("fast-import failed: %v", err)
+	}
+
+	lossy := strings.Join(result.Lossy, "\n")
+	for _, want := range []string{"run.sh has mode 100755", "link has mode 120000", "merge parent :3 dropped"} {
+		if !strings.Contains(lossy, want) {
+			t.Fatalf("expected %q among the losses, got:\n%s", want, lossy)
+		}
+	}
+	if len(result.Skipped) != 0 {
+		t.Fatalf("losses should not be reported as skipped refs: %v", result.Skipped)
+	}
+}
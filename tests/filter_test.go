@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestFilterDropsPathsAndRewritesRefs(t *testing.T) {
+	tmp := setupRepo(t)
+	t.Setenv("GT_AUTHOR_NAME", "Old Name")
+	t.Setenv("GT_AUTHOR_EMAIL", "test@example.com")
+
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "a", "secret.key": "hunter2"})
+	if _, err := vcs.CreateTag("v1", "HEAD", true, "release", false); err != nil {
+		t.Fatalf("creating tag failed: %v", err)
+	}
+	os.Remove(filepath.Join(tmp, "secret.key"))
+	second := commitFiles(t, tmp, "second", map[string]string{"docs/guide.md": "guide"})
+
+	result, err := vcs.FilterHistory(vcs.FilterOptions{
+		DropPaths:   []string{"*.key"},
+		RenamePaths: map[string]string{"docs": "doc"},
+		Authors:     map[string]string{"test@example.com": "New Name <new@example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+
+	newFirst, newSecond := result.Mapping[first], result.Mapping[second]
+	if newFirst == first || newSecond == second {
+		t.Fatalf("commits were not rewritten: %v", result.Mapping)
+	}
+	if headHash(t) != newSecond {
+		t.Fatalf("main should point at the rewritten commit")
+	}
+
+	files, err := vcs.FlattenCommit(newFirst)
+	if err != nil {
+		t.Fatalf("reading rewritten commit failed: %v", err)
+	}
+	if _, ok := files["secret.key"]; ok {
+		t.Fatalf("secret.key should be gone from the rewritten root commit")
+	}
+
+	commit, _ := vcs.ReadCommit(newSecond)
+	if commit.ParentHash != newFirst {
+		t.Fatalf("rewritten commit should have the rewritten parent")
+	}
+	if commit.Author != "New Name <new@example.com>" {
+		t.Fatalf("author was not rewritten: %q", commit.Author)
+	}
+	if _, ok, _ := vcs.FindTreeEntry(commit.TreeHash, "doc/guide.md"); !ok {
+		t.Fatalf("docs/ should have been renamed to doc/")
+	}
+	if readFile(t, tmp, "doc/guide.md") != "guide" {
+		t.Fatalf("working tree should follow the rewritten HEAD")
+	}
+
+	tagged, err := vcs.ResolveRevision("v1")
+	if err != nil || tagged != newFirst {
+		t.Fatalf("tag v1 should peel to the rewritten commit, got %s, %v", tagged, err)
+	}
+
+	saved, err := vcs.ReadFilterMap()
+	if err != nil || saved[second] != newSecond {
+		t.Fatalf("filter map was not saved: %v, %v", saved, err)
+	}
+}
+
+func TestFilterPruneEmpty(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a"})
+	commitFiles(t, tmp, "add blob", map[string]string{"big.bin": "lots of bytes"})
+	commitFiles(t, tmp, "third", map[string]string{"a.txt": "b"})
+
+	if _, err := vcs.FilterHistory(vcs.FilterOptions{DropPaths: []string{"big.bin"}, PruneEmpty: true}); err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+
+	if got := messages(t); len(got) != 2 || got[0] != "third" || got[1] != "first" {
+		t.Fatalf("unexpected history after pruning: %v", got)
+	}
+}
+
+func TestFilterRejectsCollidingRenames(t *testing.T) {
+	tmp := setupRepo(t)
+	head := commitFiles(t, tmp, "first", map[string]string{"a.txt": "a", "b.txt": "b", "lib/c.txt": "c"})
+
+	for _, renames := range []map[string]string{
+		{"a.txt": "same.txt", "b.txt": "same.txt"},
+		{"a.txt": "out", "lib": "out"},
+		{"a.txt": "out", "b.txt": "out/b.txt"},
+	} {
+		_, err := vcs.FilterHistory(vcs.FilterOptions{RenamePaths: renames})
+		if err == nil {
+			t.Fatalf("renames %v should collide", renames)
+		}
+		for from := range renames {
+			if !strings.Contains(err.Error(), from) {
+				t.Fatalf("error for %v should name %s: %v", renames, from, err)
+			}
+		}
+		if headHash(t) != head {
+			t.Fatalf("a failed filter should leave history alone")
+		}
+	}
+}
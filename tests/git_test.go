@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestExportImportGitRoundTrip(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a\n", "dir/sub/b.txt": "b\n", "dir.txt": "c\n"})
+	vcs.CreateTag("v1", "HEAD", true, "release", false)
+	second := commitFiles(t, tmp, "second\n\nwith a body", map[string]string{"a.txt": "changed\n"})
+
+	gitDir := filepath.Join(t.TempDir(), "export.git")
+	result, err := vcs.ExportGit(gitDir)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if result.Objects == 0 || len(result.Refs) != 2 {
+		t.Fatalf("unexpected export result: %+v", result)
+	}
+
+	// A second run has nothing new to convert.
+	again, err := vcs.ExportGit(gitDir)
+	if err != nil || again.Objects != 0 {
+		t.Fatalf("repeated export converted %d objects, %v", again.Objects, err)
+	}
+
+	if git, err := exec.LookPath("git"); err == nil {
+		out, err := exec.Command(git, "--git-dir", gitDir, "fsck", "--strict").CombinedOutput()
+		if err != nil {
+			t.Fatalf("git fsck failed: %v\n%s", err, out)
+		}
+		out, err = exec.Command(git, "--git-dir", gitDir, "log", "--format=%s", "main").Output()
+		if err != nil || string(out) != "second\nfirst\n" {
+			t.Fatalf("git log gave %q, %v", out, err)
+		}
+	}
+
+	// Importing into an empty repository gives back the same commits.
+	setupRepo(t)
+	if _, err := vcs.ImportGit(gitDir); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if headHash(t) != second {
+		t.Fatalf("imported main is %s, want %s", headHash(t), second)
+	}
+	if readFile(t, ".", "dir/sub/b.txt") != "b\n" {
+		t.Fatalf("import did not check out the files")
+	}
+	tag, err := vcs.ResolveObject("v1")
+	if err != nil {
+		t.Fatalf("imported tag missing: %v", err)
+	}
+	if objType, _, _ := vcs.ReadObjectType(tag); objType != "tag" {
+		t.Fatalf("v1 should stay an annotated tag")
+	}
+}
+
+func TestImportGitPacked(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command(git, args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	content := ""
+	for i := 0; i < 5; i++ {
+		content += strings.Repeat("line\n", 100) + "change\n"
+		os.WriteFile(filepath.Join(repo, "f.txt"), []byte(content), 0644)
+		run("add", "f.txt")
+		run("commit", "-q", "-m", "commit")
+	}
+	// Repack so the import has to resolve deltas.
+	run("gc", "-q", "--aggressive")
+
+	setupRepo(t)
+	result, err := vcs.ImportGit(repo)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Objects != 15 {
+		t.Fatalf("expected 15 objects, imported %d", result.Objects)
+	}
+	if readFile(t, ".", "f.txt") != content {
+		t.Fatalf("imported file content differs")
+	}
+}
+
+func TestImportGitReportsLosses(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command(git, args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(repo, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	os.Symlink("run.sh", filepath.Join(repo, "link"))
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	run("checkout", "-q", "-b", "topic")
+	os.WriteFile(filepath.Join(repo, "topic.txt"), []byte("topic\n"), 0644)
+	run("add", ".")
+	run("commit", "-q", "-m", "topic")
+	run("checkout", "-q", "main")
+	run("merge", "-q", "--no-ff", "-m", "merge", "topic")
+
+	setupRepo(t)
+	result, err := vcs.ImportGit(repo)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	lossy := strings.Join(result.Lossy, "\n")
+	for _, want := range []string{"run.sh has mode 100755", "link has mode 120000", "merge parent"} {
+		if !strings.Contains(lossy, want) {
+			t.Fatalf("expected %q among the losses, got:\n%s", want, lossy)
+		}
+	}
+	if len(result.Skipped) != 0 {
+		t.Fatalf("losses should not be reported as skipped refs: %v", result.Skipped)
+	}
+}
+
+func TestExportImportGitSubmodule(t *testing.T) {
+	lib := setupRemote(t)
+
+	super := setupRepo(t)
+	commitFiles(t, super, "base", map[string]string{"f.txt": "base\n"})
+	if err := vcs.AddSubmodule(lib, "lib"); err != nil {
+		t.Fatalf("submodule add failed: %v", err)
+	}
+	vcs.HandleCommit("add lib", super)
+	index, _ := vcs.ReadIndex()
+	gitlink := index["lib"].Hash
+
+	gitDir := filepath.Join(t.TempDir(), "export.git")
+	if _, err := vcs.ExportGit(gitDir); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if git, err := exec.LookPath("git"); err == nil {
+		out, err := exec.Command(git, "--git-dir", gitDir, "ls-tree", "main", "lib").Output()
+		if err != nil || string(out) != "160000 commit "+gitlink+"\tlib\n" {
+			t.Fatalf("git ls-tree gave %q, %v", out, err)
+		}
+	}
+
+	setupRepo(t)
+	if _, err := vcs.ImportGit(gitDir); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	entries, err := vcs.LsTree("HEAD", vcs.LsTreeOptions{Paths: []string{"lib"}})
+	if err != nil || len(entries) != 1 || entries[0].Mode != vcs.GitlinkMode || entries[0].Hash != gitlink {
+		t.Fatalf("the gitlink should survive the round trip, got %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestGrep(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{
+		"a.txt":     "foo\nfoobar\nFoo\n",
+		"dir/b.txt": "bar\nfoo bar\n",
+		"bin.dat":   "foo\x00",
+	})
+	os.Remove(filepath.Join(tmp, "bin.dat"))
+	commitFiles(t, tmp, "second", map[string]string{"a.txt": "nothing here\n"})
+
+	// The old revision is searched without checking it out.
+	files, err := vcs.Grep("foo", first, vcs.GrepOptions{})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if len(files) != 3 || files[0].Path != "a.txt" || !files[1].Binary || files[2].Path != "dir/b.txt" {
+		t.Fatalf("unexpected matches: %+v", files)
+	}
+	if len(files[0].Lines) != 2 || files[0].Lines[1] != (vcs.GrepLine{Number: 2, Text: "foobar"}) {
+		t.Fatalf("unexpected lines in a.txt: %+v", files[0].Lines)
+	}
+	if readFile(t, tmp, "a.txt") != "nothing here\n" {
+		t.Fatalf("grep should not touch the working tree")
+	}
+
+	files, _ = vcs.Grep("foo", first, vcs.GrepOptions{IgnoreCase: true, WordRegexp: true, Paths: []string{"a.txt"}})
+	if len(files) != 1 || len(files[0].Lines) != 2 || files[0].Lines[1].Text != "Foo" {
+		t.Fatalf("unexpected -i -w matches: %+v", files)
+	}
+
+	// HEAD, the index and the working tree can differ.
+	if files, _ := vcs.Grep("foo", "", vcs.GrepOptions{Paths: []string{"a.txt"}}); len(files) != 0 {
+		t.Fatalf("HEAD no longer has foo in a.txt: %+v", files)
+	}
+	writeFile(t, tmp, "a.txt", "foo again\n")
+	if files, _ := vcs.Grep("foo", "", vcs.GrepOptions{Cached: true, Paths: []string{"a.txt"}}); len(files) != 0 {
+		t.Fatalf("the index has no foo in a.txt: %+v", files)
+	}
+	files, _ = vcs.Grep("again", "", vcs.GrepOptions{Worktree: true})
+	if len(files) != 1 || files[0].Path != "a.txt" {
+		t.Fatalf("expected a match in the working tree, got %+v", files)
+	}
+
+	if _, err := vcs.Grep("(", "", vcs.GrepOptions{}); err == nil {
+		t.Fatalf("an invalid pattern should fail")
+	}
+}
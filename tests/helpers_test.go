@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+// setupRepo initializes a repository in a temporary directory and makes it
+// the working directory for the rest of the test.
+func setupRepo(t *testing.T) string {
+	t.Helper()
+
+	tmp := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	vcs.HandleInit(tmp)
+	return tmp
+}
+
+func writeFile(t *testing.T, dir string, path string, content string) {
+	t.Helper()
+
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, dir string, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, path))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func headHash(t *testing.T) string {
+	t.Helper()
+
+	hash, err := vcs.ResolveRevision("HEAD")
+	if err != nil {
+		t.Fatalf("failed to resolve HEAD: %v", err)
+	}
+	return hash
+}
+
+// commitFiles writes files below dir, stages everything and commits it,
+// returning the new HEAD.
+func commitFiles(t *testing.T, dir string, message string, files map[string]string) string {
+	t.Helper()
+
+	for path, content := range files {
+		writeFile(t, dir, path, content)
+	}
+	if err := vcs.AddToIndex([]string{"."}); err != nil {
+		t.Fatalf("failed to stage: %v", err)
+	}
+	vcs.HandleCommit(message, dir)
+	return headHash(t)
+}
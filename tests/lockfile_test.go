@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"GoTrack/vcs"
+)
+
+func TestRefLocking(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "1"})
+	second := commitFiles(t, tmp, "second", map[string]string{"a.txt": "2"})
+
+	timeout := vcs.LockTimeout
+	vcs.LockTimeout = 50 * time.Millisecond
+	defer func() { vcs.LockTimeout = timeout }()
+
+	// A lock left by another process blocks the update until it is gone.
+	lockPath := filepath.Join(tmp, ".gt", "refs", "heads", "main.lock")
+	writeFile(t, tmp, ".gt/refs/heads/main.lock", "")
+	if err := vcs.UpdateRef("HEAD", first, "blocked"); err == nil || headHash(t) != second {
+		t.Fatalf("the update should time out and leave HEAD alone, got %v", err)
+	}
+	if branches, _ := vcs.ListRefs("refs/heads"); len(branches) != 1 {
+		t.Fatalf("a lock is not a branch: %v", branches)
+	}
+	os.Remove(lockPath)
+	if err := vcs.UpdateRefFrom("HEAD", first, second, "unblocked"); err != nil || headHash(t) != first {
+		t.Fatalf("the update should succeed once the lock is gone, got %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("the lock should be released")
+	}
+
+	// Only one of several updates from the same old value wins.
+	vcs.LockTimeout = time.Second
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if vcs.UpdateRefFrom("refs/heads/race", second, "", "race") == nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Fatalf("expected exactly one update to win, %d did", won)
+	}
+
+	// Objects are renamed into place, so no temporary files stay behind.
+	filepath.Walk(filepath.Join(tmp, ".gt"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && (strings.Contains(info.Name(), ".gt-tmp-") || strings.HasSuffix(info.Name(), ".lock")) {
+			t.Fatalf("leftover file: %s", path)
+		}
+		return nil
+	})
+}
+
+func TestIndexLocking(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "1"})
+
+	timeout := vcs.LockTimeout
+	vcs.LockTimeout = 50 * time.Millisecond
+	defer func() { vcs.LockTimeout = timeout }()
+
+	writeFile(t, tmp, "b.txt", "b")
+	writeFile(t, tmp, ".gt/index.lock", "")
+	if err := vcs.AddToIndex([]string{"b.txt"}); err == nil {
+		t.Fatalf("staging should fail while the index is locked")
+	}
+	if index, _ := vcs.ReadIndex(); len(index) != 1 {
+		t.Fatalf("a locked index should be left alone, got %v", index)
+	}
+
+	os.Remove(filepath.Join(tmp, ".gt", "index.lock"))
+	if err := vcs.AddToIndex([]string{"b.txt"}); err != nil {
+		t.Fatalf("staging should succeed once the lock is gone: %v", err)
+	}
+	if index, _ := vcs.ReadIndex(); len(index) != 2 {
+		t.Fatalf("expected 2 index entries, got %v", index)
+	}
+}
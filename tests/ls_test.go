@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func treePaths(entries []vcs.TreeEntry) []string {
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Type+" "+entry.Name)
+	}
+	return paths
+}
+
+func TestLsTree(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a", "d/b.txt": "b", "d/e/c.txt": "c"})
+
+	for _, tc := range []struct {
+		opts vcs.LsTreeOptions
+		want []string
+	}{
+		{vcs.LsTreeOptions{}, []string{"blob a.txt", "tree d"}},
+		{vcs.LsTreeOptions{Recursive: true}, []string{"blob a.txt", "blob d/b.txt", "blob d/e/c.txt"}},
+		{vcs.LsTreeOptions{Recursive: true, TreesOnly: true}, []string{"tree d", "tree d/e"}},
+		{vcs.LsTreeOptions{Paths: []string{"d/e/c.txt"}}, []string{"blob d/e/c.txt"}},
+		{vcs.LsTreeOptions{Paths: []string{"d"}}, []string{"tree d"}},
+		{vcs.LsTreeOptions{Recursive: true, Paths: []string{"d/e"}}, []string{"blob d/e/c.txt"}},
+	} {
+		entries, err := vcs.LsTree("HEAD", tc.opts)
+		if err != nil {
+			t.Fatalf("ls-tree %+v failed: %v", tc.opts, err)
+		}
+		if got := treePaths(entries); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("ls-tree %+v: expected %v, got %v", tc.opts, tc.want, got)
+		}
+	}
+
+	entries, _ := vcs.LsTree("HEAD:d", vcs.LsTreeOptions{})
+	if got := treePaths(entries); strings.Join(got, ",") != "blob b.txt,tree e" {
+		t.Fatalf("a tree should be listed relative to itself, got %v", got)
+	}
+	if _, err := vcs.LsTree("HEAD:a.txt", vcs.LsTreeOptions{}); err == nil {
+		t.Fatalf("a blob is not a tree")
+	}
+}
+
+func TestLsFiles(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a", "d/b.txt": "b"})
+	writeFile(t, tmp, "a.txt", "changed")
+	os.Remove(filepath.Join(tmp, "d", "b.txt"))
+	writeFile(t, tmp, "new.txt", "new")
+	writeFile(t, tmp, "build/out.log", "log")
+	writeFile(t, tmp, ".gtignore", "*.log\n")
+
+	for _, tc := range []struct {
+		opts vcs.LsFilesOptions
+		want []string
+	}{
+		{vcs.LsFilesOptions{}, []string{"a.txt", "d/b.txt"}},
+		{vcs.LsFilesOptions{Paths: []string{"d"}}, []string{"d/b.txt"}},
+		{vcs.LsFilesOptions{Modified: true}, []string{"a.txt", "d/b.txt"}},
+		{vcs.LsFilesOptions{Others: true}, []string{".gtignore", "new.txt"}},
+		{vcs.LsFilesOptions{Ignored: true}, []string{"build/out.log"}},
+		{vcs.LsFilesOptions{Others: true, Ignored: true}, []string{"build/out.log"}},
+	} {
+		entries, err := vcs.LsFiles(tc.opts)
+		if err != nil {
+			t.Fatalf("ls-files %+v failed: %v", tc.opts, err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Path)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("ls-files %+v: expected %v, got %v", tc.opts, tc.want, got)
+		}
+	}
+}
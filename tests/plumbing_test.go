@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestPlumbing(t *testing.T) {
+	tmp := setupRepo(t)
+
+	hash, err := vcs.HashObject([]byte("hello\n"), false)
+	if err != nil || vcs.ObjectExists(hash) {
+		t.Fatalf("hashing alone should not store the blob, got %s, %v", hash, err)
+	}
+	if written, _ := vcs.HashObject([]byte("hello\n"), true); written != hash || !vcs.ObjectExists(hash) {
+		t.Fatalf("-w should store the blob under the same hash")
+	}
+
+	writeFile(t, tmp, "a.txt", "a")
+	if err := vcs.AddToIndex([]string{"a.txt"}); err != nil {
+		t.Fatalf("failed to stage: %v", err)
+	}
+	tree, err := vcs.WriteIndexTree()
+	if err != nil {
+		t.Fatalf("write-tree failed: %v", err)
+	}
+
+	root, err := vcs.CommitTree(tree, "", "root")
+	if err != nil {
+		t.Fatalf("commit-tree failed: %v", err)
+	}
+	child, _ := vcs.CommitTree(tree, root, "child")
+	if commit, _ := vcs.ReadCommit(child); commit.ParentHash != root || commit.TreeHash != tree || commit.Message != "child" {
+		t.Fatalf("unexpected commit: %+v", commit)
+	}
+	if head, _ := vcs.GetLatestCommitHash(); head != "" {
+		t.Fatalf("commit-tree should not move HEAD")
+	}
+
+	// The ref must not exist yet, then must still hold root.
+	if err := vcs.CompareAndSwapRef("refs/heads/topic", root, vcs.ZeroHash, "create"); err != nil {
+		t.Fatalf("creating the ref failed: %v", err)
+	}
+	if err := vcs.CompareAndSwapRef("refs/heads/topic", child, vcs.ZeroHash, "create"); err == nil {
+		t.Fatalf("the ref already exists")
+	}
+	if err := vcs.CompareAndSwapRef("refs/heads/topic", child, root, "advance"); err != nil {
+		t.Fatalf("advancing the ref failed: %v", err)
+	}
+	if err := vcs.CompareAndSwapRef("refs/heads/topic", root, root, "stale"); err == nil {
+		t.Fatalf("a stale old value should be rejected")
+	}
+	if hash, _ := vcs.ReadRef("refs/heads/topic"); hash != child {
+		t.Fatalf("expected topic at the child, got %s", hash)
+	}
+	if err := vcs.CompareAndSwapRef("topic", root, "", "short"); err == nil {
+		t.Fatalf("refs outside refs/ should be rejected")
+	}
+
+	if err := vcs.CompareAndSwapRef("HEAD", child, "", "reset"); err != nil || headHash(t) != child {
+		t.Fatalf("HEAD should follow its branch to the child, got %v", err)
+	}
+
+	// The handlers hand failures back so the command can exit non-zero.
+	if err := vcs.HandleUpdateRef("refs/heads/topic", root, root, ""); err == nil {
+		t.Fatalf("update-ref with a stale old value should fail")
+	}
+	if err := vcs.HandleCommitTree("missing", "", "message", true); err == nil {
+		t.Fatalf("commit-tree of an unknown tree should fail")
+	}
+	if err := vcs.HandleHashObject([]string{"missing.txt"}, false, false); err == nil {
+		t.Fatalf("hash-object of a missing file should fail")
+	}
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/constants"
+	"GoTrack/vcs"
+)
+
+// messages lists the commit messages from HEAD back to the root.
+func messages(t *testing.T) []string {
+	t.Helper()
+
+	var result []string
+	for hash := headHash(t); hash != ""; {
+		commit, err := vcs.ReadCommit(hash)
+		if err != nil {
+			t.Fatalf("failed to read commit %s: %v", hash, err)
+		}
+		result = append(result, commit.Message)
+		hash = commit.ParentHash
+	}
+	return result
+}
+
+func setupDivergedBranches(t *testing.T) (string, string) {
+	t.Helper()
+
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "base", map[string]string{"f.txt": "base\n"})
+	vcs.CreateBranch("feature", "HEAD", false)
+	vcs.HandleCheckout(tmp, "feature")
+	commitFiles(t, tmp, "one", map[string]string{"one.txt": "1\n"})
+	commitFiles(t, tmp, "two", map[string]string{"two.txt": "2\n"})
+	commitFiles(t, tmp, "three", map[string]string{"three.txt": "3\n"})
+
+	vcs.HandleCheckout(tmp, "main")
+	main := commitFiles(t, tmp, "main", map[string]string{"m.txt": "m\n"})
+	vcs.HandleCheckout(tmp, "feature")
+
+	return tmp, main
+}
+
+func TestRebaseOntoUpstream(t *testing.T) {
+	tmp, main := setupDivergedBranches(t)
+
+	if err := vcs.StartRebase("main", "", "", ""); err != nil {
+		t.Fatalf("rebase failed: %v", err)
+	}
+
+	got := strings.Join(messages(t), ",")
+	if got != "three,two,one,main,base" {
+		t.Fatalf("unexpected history after rebase: %s", got)
+	}
+	if branch, _ := vcs.CurrentBranch(); branch != "feature" {
+		t.Fatalf("HEAD should be back on feature, got %q", branch)
+	}
+	if ancestors, _ := vcs.Ancestors(headHash(t)); !ancestors[main] {
+		t.Fatalf("rebased branch should contain main")
+	}
+	if readFile(t, tmp, "m.txt") != "m\n" || readFile(t, tmp, "three.txt") != "3\n" {
+		t.Fatalf("working tree does not match the rebased branch")
+	}
+	if _, err := os.Stat(constants.RebaseDir); !os.IsNotExist(err) {
+		t.Fatalf("rebase state should be removed when done")
+	}
+}
+
+func TestRebaseOntoSkipsUpstreamCommits(t *testing.T) {
+	tmp, _ := setupDivergedBranches(t)
+
+	// Move only "three" onto main, leaving "one" and "two" behind.
+	if err := vcs.StartRebase("feature~1", "main", "", ""); err != nil {
+		t.Fatalf("rebase --onto failed: %v", err)
+	}
+
+	got := strings.Join(messages(t), ",")
+	if got != "three,main,base" {
+		t.Fatalf("unexpected history after rebase --onto: %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "one.txt")); !os.IsNotExist(err) {
+		t.Fatalf("one.txt should not be part of the rebased branch")
+	}
+}
+
+func TestRebaseTodoFile(t *testing.T) {
+	tmp, _ := setupDivergedBranches(t)
+
+	todo, err := vcs.RebaseTodo("main")
+	if err != nil || len(todo) != 3 {
+		t.Fatalf("expected three default picks, got %v (%v)", todo, err)
+	}
+
+	one := strings.Fields(todo[0])[1]
+	two := strings.Fields(todo[1])[1]
+	three := strings.Fields(todo[2])[1]
+	marker := filepath.Join(t.TempDir(), "exec-ran")
+
+	script := strings.Join([]string{
+		"# generated by a script",
+		"reword " + one + " first, reworded",
+		"fixup " + two,
+		"drop " + three,
+		"exec touch " + marker,
+	}, "\n")
+	todoFile := filepath.Join(t.TempDir(), "todo")
+	os.WriteFile(todoFile, []byte(script), 0644)
+
+	if err := vcs.StartRebase("main", "", "", todoFile); err != nil {
+		t.Fatalf("rebase with todo file failed: %v", err)
+	}
+
+	got := strings.Join(messages(t), ",")
+	if got != "first, reworded,main,base" {
+		t.Fatalf("unexpected history after scripted rebase: %s", got)
+	}
+	if readFile(t, tmp, "two.txt") != "2\n" {
+		t.Fatalf("fixup should fold two.txt into the first commit")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "three.txt")); !os.IsNotExist(err) {
+		t.Fatalf("dropped commit should not be applied")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("exec step did not run")
+	}
+}
+
+func TestRebaseSquashKeepsBothMessages(t *testing.T) {
+	setupDivergedBranches(t)
+
+	todo, _ := vcs.RebaseTodo("main")
+	script := todo[0] + "\nsquash " + strings.Fields(todo[1])[1] + "\n"
+	todoFile := filepath.Join(t.TempDir(), "todo")
+	os.WriteFile(todoFile, []byte(script), 0644)
+
+	if err := vcs.StartRebase("main", "", "", todoFile); err != nil {
+		t.Fatalf("rebase failed: %v", err)
+	}
+
+	if msgs := messages(t); msgs[0] != "one\n\ntwo" || msgs[1] != "main" {
+		t.Fatalf("unexpected history after squash: %q", msgs)
+	}
+}
+
+func TestRebaseConflictAbort(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "base", map[string]string{"f.txt": "base\n"})
+	vcs.CreateBranch("feature", "HEAD", false)
+	vcs.HandleCheckout(tmp, "feature")
+	feature := commitFiles(t, tmp, "feature", map[string]string{"f.txt": "feature\n"})
+	vcs.HandleCheckout(tmp, "main")
+	commitFiles(t, tmp, "main", map[string]string{"f.txt": "main\n"})
+	vcs.HandleCheckout(tmp, "feature")
+
+	if err := vcs.StartRebase("main", "", "", ""); err == nil {
+		t.Fatalf("expected the rebase to stop on a conflict")
+	}
+	if _, err := os.Stat(filepath.Join(constants.RebaseDir, "todo")); err != nil {
+		t.Fatalf("rebase progress should be stored in %s", constants.RebaseDir)
+	}
+
+	if err := vcs.AbortRebase(); err != nil {
+		t.Fatalf("abort failed: %v", err)
+	}
+	if branch, _ := vcs.CurrentBranch(); branch != "feature" || headHash(t) != feature {
+		t.Fatalf("abort should return to feature at its original commit")
+	}
+	if readFile(t, tmp, "f.txt") != "feature\n" {
+		t.Fatalf("abort should restore the working tree")
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/constants"
+	"GoTrack/vcs"
+)
+
+func TestReflogRecordsCommitsAndCheckouts(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"file.txt": "one"})
+	first := headHash(t)
+
+	commitFiles(t, tmp, "second", map[string]string{"file.txt": "two"})
+	second := headHash(t)
+
+	vcs.HandleCheckout(tmp, first)
+
+	entries, err := vcs.ReadReflog("HEAD")
+	if err != nil {
+		t.Fatalf("failed to read HEAD reflog: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 HEAD reflog entries, got %d", len(entries))
+	}
+	if entries[0].OldHash != vcs.ZeroHash || entries[0].NewHash != first {
+		t.Fatalf("unexpected initial entry: %+v", entries[0])
+	}
+	if entries[2].OldHash != second || entries[2].NewHash != first ||
+		!strings.HasPrefix(entries[2].Reason, "checkout:") {
+		t.Fatalf("unexpected checkout entry: %+v", entries[2])
+	}
+
+	// The branch log keeps only the commits made on it.
+	branchEntries, err := vcs.ReadReflog("refs/heads/" + constants.DefaultBranch)
+	if err != nil || len(branchEntries) != 2 {
+		t.Fatalf("expected 2 branch reflog entries, got %d (%v)", len(branchEntries), err)
+	}
+
+	for rev, want := range map[string]string{
+		"HEAD@{0}": first,
+		"HEAD@{1}": second,
+		"@{2}":     first,
+		"main@{0}": second,
+	} {
+		got, err := vcs.ResolveRevision(rev)
+		if err != nil || got != want {
+			t.Fatalf("%s resolved to %q (%v), want %s", rev, got, err, want)
+		}
+	}
+
+	if _, err := vcs.ResolveRevision("HEAD@{3}"); err == nil {
+		t.Fatalf("expected an error for a reflog entry that does not exist")
+	}
+}
+
+func TestCheckoutBranchAttachesHead(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"file.txt": "one"})
+
+	if _, err := vcs.CreateBranch("feature", "HEAD", false); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+	vcs.HandleCheckout(tmp, "feature")
+
+	head, err := os.ReadFile(filepath.Join(constants.GTDir, "HEAD"))
+	if err != nil || strings.TrimSpace(string(head)) != "ref: refs/heads/feature" {
+		t.Fatalf("HEAD = %q (%v), want a ref to feature", head, err)
+	}
+
+	commitFiles(t, tmp, "on feature", map[string]string{"file.txt": "two"})
+
+	feature, _ := vcs.ReadRef("refs/heads/feature")
+	main, _ := vcs.ReadRef("refs/heads/main")
+	if feature == main || feature != headHash(t) {
+		t.Fatalf("commit should only move the checked out branch")
+	}
+}
+
+func TestDeleteBranchRejectsInvalidNames(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"file.txt": "one"})
+
+	if _, err := vcs.DeleteBranch("../x/../../HEAD"); err == nil {
+		t.Fatalf("expected an invalid branch name to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(constants.GTDir, "HEAD")); err != nil {
+		t.Fatalf("HEAD must survive, got %v", err)
+	}
+}
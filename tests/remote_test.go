@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoTrack/constants"
+	"GoTrack/vcs"
+)
+
+// setupRemote creates a repository with one commit in a directory of its
+// own and returns its path, leaving the test in a fresh empty directory.
+func setupRemote(t *testing.T) string {
+	t.Helper()
+
+	origin := setupRepo(t)
+	commitFiles(t, origin, "first", map[string]string{"a.txt": "a", "dir/b.txt": "b"})
+	if _, err := vcs.CreateTag("v1", "HEAD", false, "", false); err != nil {
+		t.Fatalf("creating tag failed: %v", err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	return origin
+}
+
+func TestCloneFetchPush(t *testing.T) {
+	origin := setupRemote(t)
+
+	if err := vcs.Clone(origin, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if readFile(t, clone, "dir/b.txt") != "b" {
+		t.Fatalf("clone did not check out the files")
+	}
+	if tracking, _ := vcs.ReadRef("refs/remotes/origin/main"); tracking != headHash(t) {
+		t.Fatalf("origin/main should match the cloned main")
+	}
+	if tag, _ := vcs.ReadRef("refs/tags/v1"); tag == "" {
+		t.Fatalf("clone should fetch tags")
+	}
+
+	// New commits on the remote arrive with fetch.
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	upstream := commitFiles(t, origin, "second", map[string]string{"c.txt": "c"})
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	updates, err := vcs.Fetch("origin")
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if len(updates) != 1 || updates[0].NewHash != upstream {
+		t.Fatalf("unexpected fetch updates: %v", updates)
+	}
+	if !vcs.ObjectExists(upstream) {
+		t.Fatalf("fetch did not copy the new commit")
+	}
+	if resolved, _ := vcs.ResolveRevision("origin/main"); resolved != upstream {
+		t.Fatalf("origin/main should resolve to the fetched commit")
+	}
+
+	// Pushing a new branch copies its objects to the remote.
+	vcs.CreateBranch("feature", "HEAD", false)
+	if err := vcs.SetHead("refs/heads/feature", "checkout"); err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	pushed := commitFiles(t, clone, "feature work", map[string]string{"f.txt": "f"})
+	if _, err := vcs.Push("origin", "feature", false); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if !fileExists(filepath.Join(origin, ".gt", "objects", pushed[:2], pushed[2:])) {
+		t.Fatalf("push did not copy the commit")
+	}
+	if remote, _ := os.ReadFile(filepath.Join(origin, ".gt", "refs", "heads", "feature")); string(remote) != pushed+"\n" {
+		t.Fatalf("push did not update the remote branch")
+	}
+
+	// The branch checked out in the remote is protected.
+	if _, err := vcs.Push("origin", "main", false); err == nil {
+		t.Fatalf("pushing to the remote's checked out branch should fail")
+	}
+}
+
+func TestPushRejectsNonFastForward(t *testing.T) {
+	origin := setupRemote(t)
+
+	if err := vcs.Clone(origin, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	vcs.CreateBranch("topic", "HEAD", false)
+	vcs.SetHead("refs/heads/topic", "checkout")
+	commitFiles(t, clone, "one", map[string]string{"t.txt": "1"})
+	if _, err := vcs.Push("origin", "topic", false); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+
+	// Rewrite the pushed commit so the remote one is no longer an ancestor.
+	vcs.ResetTo("HEAD~1", vcs.ResetHard)
+	commitFiles(t, clone, "other", map[string]string{"t.txt": "2"})
+
+	if _, err := vcs.Push("origin", "topic", false); err == nil {
+		t.Fatalf("non-fast-forward push should be rejected")
+	}
+	if _, err := vcs.Push("origin", "topic", true); err != nil {
+		t.Fatalf("forced push failed: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestInterruptedFetchLeavesNoDanglingObjects(t *testing.T) {
+	origin := setupRemote(t)
+	if err := vcs.Clone(origin, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+
+	// Pick content whose blob directory the clone does not have yet.
+	content := ""
+	for i := 0; content == ""; i++ {
+		candidate := fmt.Sprintf("new %d", i)
+		if !fileExists(filepath.Join(clone, ".gt", "objects", vcs.HashContent([]byte(candidate))[:2])) {
+			content = candidate
+		}
+	}
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	upstream := commitFiles(t, origin, "second", map[string]string{"new.txt": content})
+
+	// A file where the blob's directory belongs makes the fetch fail part way.
+	blocker := filepath.Join(clone, ".gt", "objects", vcs.HashContent([]byte(content))[:2])
+	writeFile(t, clone, filepath.Join(".gt", "objects", filepath.Base(blocker)), "")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if _, err := vcs.Fetch("origin"); err == nil {
+		t.Fatalf("the fetch should fail while the blob cannot be written")
+	}
+	if vcs.ObjectExists(upstream) {
+		t.Fatalf("the commit must not be stored before its tree and blobs")
+	}
+
+	// Once the problem is gone a fetch completes the history.
+	os.Remove(blocker)
+	if _, err := vcs.Fetch("origin"); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if files, err := vcs.FlattenCommit(upstream); err != nil || !vcs.ObjectExists(files["new.txt"].Hash) {
+		t.Fatalf("the fetched commit should be complete, got %v", err)
+	}
+}
+
+func TestCloneRejectsTreesThatEscape(t *testing.T) {
+	origin := setupRepo(t)
+	blob, err := vcs.WriteBlobContent([]byte("pwned"), constants.ObjectsDir)
+	if err != nil {
+		t.Fatalf("writing blob failed: %v", err)
+	}
+	data := []byte("100644 ../../escaped " + blob + "\n")
+	tree := vcs.TreeEntry{
+		Hash:    vcs.HashContent(data),
+		Content: append([]byte(fmt.Sprintf("tree %d\000", len(data))), data...),
+	}
+	vcs.WriteTree(&tree, constants.ObjectsDir)
+	commit := vcs.WriteCommit(tree.Hash, "", "evil", "escape", constants.ObjectsDir)
+	if err := vcs.UpdateRef("refs/heads/main", commit.Hash, "evil"); err != nil {
+		t.Fatalf("updating main failed: %v", err)
+	}
+
+	if _, err := vcs.FlattenTree(tree.Hash); err == nil {
+		t.Fatalf("flattening a tree with a bad entry name should fail")
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if err := vcs.Clone(origin, filepath.Join("a", "b", "clone"), vcs.FetchOptions{}); err == nil {
+		t.Fatalf("cloning a tree with a bad entry name should fail")
+	}
+	if fileExists(filepath.Join(dir, "escaped")) || fileExists(filepath.Join(dir, "a", "escaped")) {
+		t.Fatalf("a tree entry was written outside the clone")
+	}
+}
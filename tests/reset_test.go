@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestResetModes(t *testing.T) {
+	tmp := setupRepo(t)
+
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "one", "dir/b.txt": "b"})
+	os.RemoveAll(filepath.Join(tmp, "dir"))
+	second := commitFiles(t, tmp, "second", map[string]string{"a.txt": "two", "c.txt": "c"})
+
+	// --soft only moves the branch.
+	if _, err := vcs.ResetTo("HEAD~1", vcs.ResetSoft); err != nil {
+		t.Fatalf("soft reset failed: %v", err)
+	}
+	if headHash(t) != first {
+		t.Fatalf("soft reset did not move HEAD")
+	}
+	index, _ := vcs.ReadIndex()
+	if _, ok := index["c.txt"]; !ok {
+		t.Fatalf("soft reset should keep the index")
+	}
+
+	// --mixed also resets the index but not the working tree.
+	if _, err := vcs.ResetTo(second, vcs.ResetSoft); err != nil {
+		t.Fatalf("soft reset failed: %v", err)
+	}
+	if _, err := vcs.ResetTo(first, vcs.ResetMixed); err != nil {
+		t.Fatalf("mixed reset failed: %v", err)
+	}
+	index, _ = vcs.ReadIndex()
+	if _, ok := index["c.txt"]; ok {
+		t.Fatalf("mixed reset should drop c.txt from the index")
+	}
+	if readFile(t, tmp, "a.txt") != "two" {
+		t.Fatalf("mixed reset should not touch the working tree")
+	}
+
+	// --hard rewrites tracked files and leaves untracked ones alone.
+	writeFile(t, tmp, "untracked.txt", "keep me")
+	if _, err := vcs.ResetTo(second, vcs.ResetSoft); err != nil {
+		t.Fatalf("soft reset failed: %v", err)
+	}
+	vcs.HandleAdd([]string{"c.txt"})
+	if _, err := vcs.ResetTo("HEAD~", vcs.ResetHard); err != nil {
+		t.Fatalf("hard reset failed: %v", err)
+	}
+	if readFile(t, tmp, "a.txt") != "one" || readFile(t, tmp, "dir/b.txt") != "b" {
+		t.Fatalf("hard reset did not restore the tracked files")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("hard reset should remove c.txt")
+	}
+	if readFile(t, tmp, "untracked.txt") != "keep me" {
+		t.Fatalf("hard reset should not touch untracked files")
+	}
+
+	// The old tip is still reachable through the reflog.
+	if hash, err := vcs.ResolveRevision("HEAD@{1}"); err != nil || hash != second {
+		t.Fatalf("HEAD@{1} = %q (%v), want %s", hash, err, second)
+	}
+}
+
+func TestResetPathsUnstages(t *testing.T) {
+	tmp := setupRepo(t)
+
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "one", "b.txt": "b"})
+	writeFile(t, tmp, "a.txt", "changed")
+	writeFile(t, tmp, "new.txt", "new")
+	vcs.HandleAdd([]string{"a.txt", "new.txt"})
+
+	if err := vcs.ResetPaths("HEAD", []string{"a.txt", "new.txt"}); err != nil {
+		t.Fatalf("path reset failed: %v", err)
+	}
+
+	index, _ := vcs.ReadIndex()
+	committed, _ := vcs.FlattenCommit(first)
+	if index["a.txt"].Hash != committed["a.txt"].Hash {
+		t.Fatalf("a.txt should be unstaged back to the committed version")
+	}
+	if _, ok := index["new.txt"]; ok {
+		t.Fatalf("new.txt should no longer be staged")
+	}
+	if headHash(t) != first || readFile(t, tmp, "a.txt") != "changed" {
+		t.Fatalf("path reset should not move HEAD or touch the working tree")
+	}
+}
+
+func TestCommitRecordsOnlyTheIndex(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "one", "b.txt": "b"})
+
+	writeFile(t, tmp, "a.txt", "unstaged")
+	writeFile(t, tmp, "b.txt", "staged")
+	writeFile(t, tmp, "new.txt", "untracked")
+	if err := vcs.AddToIndex([]string{"b.txt"}); err != nil {
+		t.Fatalf("failed to stage: %v", err)
+	}
+	vcs.HandleCommit("second", tmp)
+
+	files, _ := vcs.FlattenCommit(headHash(t))
+	if files["a.txt"].Hash != vcs.HashContent([]byte("one")) || files["b.txt"].Hash != vcs.HashContent([]byte("staged")) {
+		t.Fatalf("only the staged change should be committed: %v", files)
+	}
+	if _, ok := files["new.txt"]; ok {
+		t.Fatalf("untracked files should not be committed")
+	}
+
+	// Unstaging takes a change back out of the next commit.
+	vcs.AddToIndex([]string{"a.txt"})
+	vcs.ResetPaths("HEAD", []string{"a.txt"})
+	vcs.HandleCommit("third", tmp)
+	if files, _ := vcs.FlattenCommit(headHash(t)); files["a.txt"].Hash != vcs.HashContent([]byte("one")) {
+		t.Fatalf("a reset path should not be committed")
+	}
+	if readFile(t, tmp, "a.txt") != "unstaged" {
+		t.Fatalf("the working tree should keep the unstaged change")
+	}
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestRestoreFromIndexAndCommit(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "a1", "dir/b.txt": "b1", "dir/sub/c.txt": "c1"})
+	commitFiles(t, tmp, "second", map[string]string{"a.txt": "a2", "dir/b.txt": "b2", "dir/sub/c.txt": "c2"})
+
+	writeFile(t, tmp, "a.txt", "dirty")
+	writeFile(t, tmp, "dir/b.txt", "dirty")
+	writeFile(t, tmp, "other.txt", "untouched")
+
+	// From the index: only the named file changes.
+	if err := vcs.RestorePaths("", []string{"a.txt"}, false, true); err != nil {
+		t.Fatalf("restore from index failed: %v", err)
+	}
+	if readFile(t, tmp, "a.txt") != "a2" || readFile(t, tmp, "dir/b.txt") != "dirty" {
+		t.Fatalf("restore from index touched the wrong files")
+	}
+
+	// A subdirectory from an older commit, without staging it.
+	if err := vcs.RestorePaths("HEAD~1", []string{"dir"}, false, true); err != nil {
+		t.Fatalf("restore from commit failed: %v", err)
+	}
+	if readFile(t, tmp, "dir/b.txt") != "b1" || readFile(t, tmp, "dir/sub/c.txt") != "c1" {
+		t.Fatalf("directory was not restored from HEAD~1")
+	}
+	if readFile(t, tmp, "a.txt") != "a2" || readFile(t, tmp, "other.txt") != "untouched" {
+		t.Fatalf("restore touched files outside the pathspec")
+	}
+	index, _ := vcs.ReadIndex()
+	if index["dir/b.txt"].Hash != vcs.HashContent([]byte("b2")) {
+		t.Fatalf("restoring only the working tree should leave the index alone")
+	}
+
+	// --staged --worktree updates both.
+	if err := vcs.RestorePaths("HEAD~1", []string{"a.txt"}, true, true); err != nil {
+		t.Fatalf("staged restore failed: %v", err)
+	}
+	index, _ = vcs.ReadIndex()
+	if readFile(t, tmp, "a.txt") != "a1" || index["a.txt"].Hash != vcs.HashContent([]byte("a1")) {
+		t.Fatalf("a.txt should be restored in the index and working tree")
+	}
+
+	if err := vcs.RestorePaths("HEAD", []string{"missing.txt"}, false, true); err == nil {
+		t.Fatalf("expected an error for a path that is not in the commit")
+	}
+}
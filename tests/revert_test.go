@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/constants"
+	"GoTrack/vcs"
+)
+
+func TestMergeFiles(t *testing.T) {
+	base := []byte("a\nb\nc\nd\ne\n")
+	ours := []byte("a\nB\nc\nd\ne\n")
+	theirs := []byte("a\nb\nc\nd\nE\n")
+
+	merged, conflict := vcs.MergeFiles(base, ours, theirs, "ours", "theirs")
+	if conflict || string(merged) != "a\nB\nc\nd\nE\n" {
+		t.Fatalf("unexpected clean merge result (conflict=%v):\n%s", conflict, merged)
+	}
+
+	merged, conflict = vcs.MergeFiles(base, ours, []byte("a\nX\nc\nd\ne\n"), "ours", "theirs")
+	want := "a\n<<<<<<< ours\nB\n=======\nX\n>>>>>>> theirs\nc\nd\ne\n"
+	if !conflict || string(merged) != want {
+		t.Fatalf("unexpected conflict result (conflict=%v):\n%s", conflict, merged)
+	}
+}
+
+func TestRevertCommit(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"f.txt": "a\nb\nc\nd\ne\n", "keep.txt": "keep"})
+	second := commitFiles(t, tmp, "second", map[string]string{"f.txt": "a\nB\nc\nd\ne\n", "added.txt": "added"})
+	commitFiles(t, tmp, "third", map[string]string{"f.txt": "a\nB\nc\nd\nE\n"})
+
+	if err := vcs.StartSequence("revert", []string{second}, false); err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+
+	if got := readFile(t, tmp, "f.txt"); got != "a\nb\nc\nd\nE\n" {
+		t.Fatalf("unexpected content after revert:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "added.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file added by the reverted commit should be removed")
+	}
+
+	head, err := vcs.ReadCommit(headHash(t))
+	if err != nil {
+		t.Fatalf("failed to read HEAD commit: %v", err)
+	}
+	if !strings.Contains(head.Message, "This reverts commit "+second) {
+		t.Fatalf("revert message does not reference the commit: %q", head.Message)
+	}
+}
+
+func TestRevertConflictContinueAndAbort(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"f.txt": "a\nb\nc\n"})
+	second := commitFiles(t, tmp, "second", map[string]string{"f.txt": "a\nB\nc\n"})
+	third := commitFiles(t, tmp, "third", map[string]string{"f.txt": "a\nX\nc\n"})
+
+	if err := vcs.StartSequence("revert", []string{second}, false); err == nil {
+		t.Fatalf("expected the revert to stop on a conflict")
+	}
+	if !strings.Contains(readFile(t, tmp, "f.txt"), "<<<<<<< HEAD") {
+		t.Fatalf("conflict markers were not written")
+	}
+	if _, err := os.Stat(constants.SequencerDir); err != nil {
+		t.Fatalf("revert state should be kept in %s", constants.SequencerDir)
+	}
+
+	// Aborting restores the original commit and content.
+	if err := vcs.AbortSequence("revert"); err != nil {
+		t.Fatalf("abort failed: %v", err)
+	}
+	if headHash(t) != third || readFile(t, tmp, "f.txt") != "a\nX\nc\n" {
+		t.Fatalf("abort did not restore the original state")
+	}
+
+	// Resolving and continuing records the revert commit.
+	if err := vcs.StartSequence("revert", []string{second}, false); err == nil {
+		t.Fatalf("expected the revert to stop on a conflict")
+	}
+	if err := vcs.ContinueSequence("revert"); err == nil {
+		t.Fatalf("continue should refuse while conflict markers remain")
+	}
+	writeFile(t, tmp, "f.txt", "a\nb\nX\nc\n")
+	if err := vcs.ContinueSequence("revert"); err != nil {
+		t.Fatalf("continue failed: %v", err)
+	}
+
+	head, _ := vcs.ReadCommit(headHash(t))
+	if head.ParentHash != third || !strings.HasPrefix(head.Message, "Revert \"second\"") {
+		t.Fatalf("unexpected commit after continue: %+v", head)
+	}
+	files, _ := vcs.FlattenCommit(head.Hash)
+	if files["f.txt"].Hash != vcs.HashContent([]byte("a\nb\nX\nc\n")) {
+		t.Fatalf("the resolved content was not committed")
+	}
+	if _, err := os.Stat(constants.SequencerDir); !os.IsNotExist(err) {
+		t.Fatalf("revert state should be removed when done")
+	}
+}
+
+func TestRevertNoCommit(t *testing.T) {
+	tmp := setupRepo(t)
+
+	commitFiles(t, tmp, "first", map[string]string{"f.txt": "one"})
+	second := commitFiles(t, tmp, "second", map[string]string{"f.txt": "two"})
+
+	if err := vcs.StartSequence("revert", []string{"HEAD"}, true); err != nil {
+		t.Fatalf("revert --no-commit failed: %v", err)
+	}
+
+	if headHash(t) != second {
+		t.Fatalf("--no-commit should not create a commit")
+	}
+	index, _ := vcs.ReadIndex()
+	if readFile(t, tmp, "f.txt") != "one" || index["f.txt"].Hash != vcs.HashContent([]byte("one")) {
+		t.Fatalf("the inverse change should be applied to the index and working tree")
+	}
+}
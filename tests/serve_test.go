@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestHTTPCloneFetchPush(t *testing.T) {
+	origin := setupRemote(t)
+
+	server, err := vcs.NewServer(origin)
+	if err != nil {
+		t.Fatalf("creating server failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if err := vcs.Clone(ts.URL, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if readFile(t, clone, "dir/b.txt") != "b" {
+		t.Fatalf("clone did not check out the files")
+	}
+	if tag, _ := vcs.ReadRef("refs/tags/v1"); tag == "" {
+		t.Fatalf("clone should fetch tags")
+	}
+
+	// Push a new branch over HTTP.
+	vcs.CreateBranch("feature", "HEAD", false)
+	vcs.SetHead("refs/heads/feature", "checkout")
+	pushed := commitFiles(t, clone, "feature work", map[string]string{"f.txt": "f"})
+	if _, err := vcs.Push("origin", "feature", false); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if remote, _ := os.ReadFile(filepath.Join(origin, ".gt", "refs", "heads", "feature")); string(remote) != pushed+"\n" {
+		t.Fatalf("push did not update the remote branch")
+	}
+	if !fileExists(filepath.Join(origin, ".gt", "objects", pushed[:2], pushed[2:])) {
+		t.Fatalf("push did not send the commit")
+	}
+
+	// The server refuses to move the branch it has checked out.
+	commitFiles(t, clone, "more", map[string]string{"g.txt": "g"})
+	vcs.CreateBranch("main", "HEAD", true)
+	if _, err := vcs.Push("origin", "main", false); err == nil {
+		t.Fatalf("pushing to the checked out branch should be rejected")
+	}
+
+	// Commits made on the server arrive with fetch.
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	upstream := commitFiles(t, origin, "upstream", map[string]string{"u.txt": "u"})
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if _, err := vcs.Fetch("origin"); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if resolved, _ := vcs.ResolveRevision("origin/main"); resolved != upstream {
+		t.Fatalf("origin/main should resolve to the fetched commit")
+	}
+	files, err := vcs.FlattenCommit(upstream)
+	if err != nil || files["u.txt"].Hash == "" {
+		t.Fatalf("fetched commit is incomplete: %v", err)
+	}
+	if _, err := vcs.ReadObject(files["u.txt"].Hash); err != nil {
+		t.Fatalf("fetch did not send the new blob: %v", err)
+	}
+}
+
+func TestServeRejectsBadRequests(t *testing.T) {
+	origin := setupRemote(t)
+	writeFile(t, origin, "secret", "outside the objects directory")
+
+	server, err := vcs.NewServer(origin)
+	if err != nil {
+		t.Fatalf("creating server failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for _, body := range []string{
+		"want ../../secret\ndone\n",
+		"want a\ndone\n",
+		"have ../../../secret\ndone\n",
+	} {
+		resp, err := http.Post(ts.URL+"/upload-pack", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+
+	// A pack announcing a huge object is rejected once the bytes run out.
+	huge := vcs.ZeroHash + " " + vcs.ZeroHash + " refs/heads/x\n\nGTPACK 1\n" + strings.Repeat("a", 40) + " 1000000000000\nshort"
+	resp, err := http.Post(ts.URL+"/receive-pack", "text/plain", strings.NewReader(huge))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a truncated pack, got %d", resp.StatusCode)
+	}
+}
+
+func TestCloneRejectsRefsOutsideRefs(t *testing.T) {
+	for _, advertisement := range []string{
+		strings.Repeat("a", 40) + " refs/tags/../../../pwned\n",
+		strings.Repeat("a", 40) + " HEAD\n",
+		"symref HEAD refs/heads/../../../pwned\n",
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(advertisement))
+		}))
+
+		dir := filepath.Join(t.TempDir(), "dst")
+		if err := vcs.Clone(ts.URL, dir, vcs.FetchOptions{}); err == nil {
+			t.Fatalf("%q: expected the clone to be refused", advertisement)
+		}
+		ts.Close()
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "pwned")); err == nil {
+			t.Fatalf("%q: a ref was written outside the repository", advertisement)
+		}
+	}
+}
+
+func TestServeReportsMissingObjects(t *testing.T) {
+	origin := setupRemote(t)
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	first := headHash(t)
+	commitFiles(t, origin, "second", map[string]string{"a.txt": "changed"})
+	// Lose the first commit without marking the repository shallow; only
+	// main can still lead to it.
+	os.Remove(filepath.Join(origin, ".gt", "refs", "tags", "v1"))
+	if err := os.Remove(filepath.Join(origin, ".gt", "objects", first[:2], first[2:])); err != nil {
+		t.Fatalf("removing object failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	server, err := vcs.NewServer(origin)
+	if err != nil {
+		t.Fatalf("creating server failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if err := vcs.Clone(ts.URL, "clone", vcs.FetchOptions{}); err == nil || !strings.Contains(err.Error(), first) {
+		t.Fatalf("clone should report the missing commit, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestShallowClone(t *testing.T) {
+	origin := setupRemote(t)
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	commitFiles(t, origin, "second", map[string]string{"a.txt": "a2"})
+	third := commitFiles(t, origin, "third", map[string]string{"a.txt": "a3"})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if err := vcs.Clone(origin, "clone", vcs.FetchOptions{Depth: 2}); err != nil {
+		t.Fatalf("shallow clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	thirdCommit, _ := vcs.ReadCommit(third)
+	shallow, _ := vcs.ReadShallow()
+	if len(shallow) != 1 || !shallow[thirdCommit.ParentHash] {
+		t.Fatalf("expected the second commit as the shallow boundary, got %v", shallow)
+	}
+
+	commits, err := vcs.RevList(headHash(t), "")
+	if err != nil {
+		t.Fatalf("rev-list failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits in the shallow history, got %d", len(commits))
+	}
+
+	base, err := vcs.MergeBase(third, thirdCommit.ParentHash)
+	if err != nil || base != thirdCommit.ParentHash {
+		t.Fatalf("merge base should be the boundary commit, got %s: %v", base, err)
+	}
+}
+
+func TestShallowHistoryWalks(t *testing.T) {
+	origin := setupRemote(t)
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	second := commitFiles(t, origin, "second", map[string]string{"a.txt": "a2"})
+	commitFiles(t, origin, "third", map[string]string{"a.txt": "a3"})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if err := vcs.Clone(origin, "clone", vcs.FetchOptions{Depth: 2}); err != nil {
+		t.Fatalf("shallow clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if _, err := vcs.ResolveRevision("HEAD~2"); err == nil || !strings.Contains(err.Error(), "shallow") {
+		t.Fatalf("walking past the boundary should name the shallow clone, got %v", err)
+	}
+	if err := vcs.StartSequence("revert", []string{second}, false); err == nil || !strings.Contains(err.Error(), "shallow") {
+		t.Fatalf("reverting the boundary commit should name the shallow clone, got %v", err)
+	}
+	if _, err := vcs.ExportGit(filepath.Join(t.TempDir(), "export.git")); err == nil || !strings.Contains(err.Error(), "shallow") {
+		t.Fatalf("exporting to Git should name the shallow clone, got %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := vcs.FastExport(&out, []string{"main"}); err != nil {
+		t.Fatalf("fast-export failed: %v", err)
+	}
+	// One "from" links third to second, the other is the final reset.
+	if strings.Count(out.String(), "\ncommit ") != 2 || strings.Count(out.String(), "\nfrom ") != 2 {
+		t.Fatalf("fast-export should start at the boundary commit:\n%s", out.String())
+	}
+
+	result, err := vcs.FilterHistory(vcs.FilterOptions{DropPaths: []string{"dir/b.txt"}})
+	if err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+	rewritten, _ := vcs.ReadCommit(result.Mapping[second])
+	shallow, _ := vcs.ReadShallow()
+	if rewritten.ParentHash == "" || !shallow[rewritten.Hash] {
+		t.Fatalf("the rewritten boundary commit should keep its parent and stay shallow")
+	}
+	if commits, err := vcs.RevList(headHash(t), ""); err != nil || len(commits) != 2 {
+		t.Fatalf("expected 2 commits after filtering, got %d: %v", len(commits), err)
+	}
+}
+
+func TestPartialClone(t *testing.T) {
+	origin := setupRemote(t)
+	if err := os.Chdir(origin); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	first := headHash(t)
+	firstFiles, _ := vcs.FlattenCommit(first)
+	commitFiles(t, origin, "second", map[string]string{"a.txt": "changed"})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	server, err := vcs.NewServer(origin)
+	if err != nil {
+		t.Fatalf("creating server failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if err := vcs.Clone(ts.URL, "clone", vcs.FetchOptions{BlobNone: true}); err != nil {
+		t.Fatalf("partial clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if readFile(t, clone, "a.txt") != "changed" {
+		t.Fatalf("partial clone should check out the files")
+	}
+
+	// The old version of a.txt was left on the remote until it is read.
+	oldBlob := firstFiles["a.txt"].Hash
+	if vcs.ObjectExists(oldBlob) {
+		t.Fatalf("partial clone should not fetch old blobs")
+	}
+	content, err := vcs.ReadObject(oldBlob)
+	if err != nil || string(content) != "a" {
+		t.Fatalf("reading a missing blob should fetch it, got %q: %v", content, err)
+	}
+	if !vcs.ObjectExists(oldBlob) {
+		t.Fatalf("the fetched blob should be stored")
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestShow(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"a.txt": "1\n2\n3\n", "gone.txt": "bye\n"})
+	if _, err := vcs.CreateTag("v1", "HEAD", true, "release one", false); err != nil {
+		t.Fatalf("failed to tag: %v", err)
+	}
+	os.Remove(filepath.Join(tmp, "gone.txt"))
+	second := commitFiles(t, tmp, "second", map[string]string{"a.txt": "1\ntwo\n3\n", "dir/new.txt": "new"})
+
+	var out bytes.Buffer
+	if err := vcs.Show(&out, "HEAD"); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	for _, want := range []string{
+		"commit " + second + "\n",
+		"    second\n",
+		"--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n",
+		"new file mode 100644\n--- /dev/null\n+++ b/dir/new.txt\n@@ -0,0 +1 @@\n+new\n\\ No newline at end of file\n",
+		"deleted file mode 100644\n--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("show output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	vcs.Show(&out, "HEAD:")
+	if out.String() != "tree HEAD:\n\na.txt\ndir/\n" {
+		t.Fatalf("unexpected tree listing:\n%s", out.String())
+	}
+
+	out.Reset()
+	vcs.Show(&out, "v1:gone.txt")
+	if out.String() != "bye\n" {
+		t.Fatalf("unexpected blob content: %q", out.String())
+	}
+
+	out.Reset()
+	vcs.Show(&out, "v1")
+	if !strings.HasPrefix(out.String(), "tag v1\n") || !strings.Contains(out.String(), "release one\n\ncommit ") {
+		t.Fatalf("a tag should be shown followed by its commit:\n%s", out.String())
+	}
+
+	blob, _ := vcs.ResolveObject("HEAD:dir/new.txt")
+	if objType, size, err := vcs.ReadObjectHeader(blob); err != nil || objType != "blob" || size != 3 {
+		t.Fatalf("unexpected header: %s %d, %v", objType, size, err)
+	}
+	if staged, _ := vcs.ResolveObject(":dir/new.txt"); staged != blob {
+		t.Fatalf(":<path> should name the staged blob")
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i) + "\n"
+		a = append(a, line)
+		if i != 2 && i != 18 {
+			b = append(b, line)
+		}
+	}
+
+	diff := vcs.UnifiedDiff(a, b, 3)
+	if strings.Count(diff, "@@ -") != 2 || !strings.Contains(diff, "@@ -1,5 +1,4 @@\n") || !strings.Contains(diff, "@@ -15,6 +14,5 @@\n") {
+		t.Fatalf("expected two separate hunks:\n%s", diff)
+	}
+	if vcs.UnifiedDiff(a, a, 3) != "" {
+		t.Fatalf("equal input should produce no hunks")
+	}
+}
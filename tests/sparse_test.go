@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestSparseCheckoutCone(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{
+		"root.txt":    "root",
+		"a/top.txt":   "a",
+		"a/b/in.txt":  "in",
+		"a/c/out.txt": "out",
+		"d/out.txt":   "out",
+	})
+
+	result, err := vcs.SetSparseCheckout([]string{"a/b"}, true)
+	if err != nil {
+		t.Fatalf("sparse-checkout set failed: %v", err)
+	}
+	if len(result.Removed) != 2 {
+		t.Fatalf("expected two files to leave the working tree, got %v", result.Removed)
+	}
+	for _, path := range []string{"root.txt", "a/top.txt", "a/b/in.txt"} {
+		if !fileExists(filepath.Join(tmp, path)) {
+			t.Fatalf("%s should be checked out", path)
+		}
+	}
+	for _, path := range []string{"a/c", "d"} {
+		if fileExists(filepath.Join(tmp, path)) {
+			t.Fatalf("%s should not be checked out", path)
+		}
+	}
+
+	status, err := vcs.GetStatus()
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(status.Unstaged) != 0 || !status.Sparse {
+		t.Fatalf("files outside the cone should not show as deleted: %+v", status)
+	}
+
+	// Commits keep the files outside the cone.
+	second := commitFiles(t, tmp, "second", map[string]string{"a/b/in.txt": "changed"})
+	files, _ := vcs.FlattenCommit(second)
+	if len(files) != 5 {
+		t.Fatalf("expected 5 files in the commit, got %v", files)
+	}
+	oldFiles, _ := vcs.FlattenCommit(first)
+	if files["d/out.txt"] != oldFiles["d/out.txt"] {
+		t.Fatalf("d/out.txt should be carried forward unchanged")
+	}
+
+	// Checkout only writes the cone.
+	vcs.HandleCheckout(tmp, first)
+	if fileExists(filepath.Join(tmp, "d")) || readFile(t, tmp, "a/b/in.txt") != "in" {
+		t.Fatalf("checkout should respect the sparse checkout")
+	}
+
+	if _, err := vcs.DisableSparseCheckout(); err != nil {
+		t.Fatalf("sparse-checkout disable failed: %v", err)
+	}
+	if readFile(t, tmp, "d/out.txt") != "out" {
+		t.Fatalf("disable should check out every file again")
+	}
+}
+
+func TestSparseCheckoutPatterns(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{
+		"README":          "readme",
+		"docs/guide.md":   "guide",
+		"src/main.go":     "main",
+		"src/vendor/x.md": "vendored",
+	})
+
+	if _, err := vcs.SetSparseCheckout([]string{"*.md", "!src/", "/README"}, false); err != nil {
+		t.Fatalf("sparse-checkout set failed: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"README":          true,
+		"docs/guide.md":   true,
+		"src/main.go":     false,
+		"src/vendor/x.md": false,
+	} {
+		if fileExists(filepath.Join(tmp, path)) != want {
+			t.Fatalf("%s checked out: %v, want %v", path, !want, want)
+		}
+	}
+
+	// A file that comes into the patterns after the fact is written.
+	if _, err := vcs.AddSparseCheckout([]string{"src/main.go"}); err != nil {
+		t.Fatalf("sparse-checkout add failed: %v", err)
+	}
+	if readFile(t, tmp, "src/main.go") != "main" {
+		t.Fatalf("src/main.go should be checked out after add")
+	}
+
+	// Adding everything must not unstage files outside the patterns.
+	vcs.HandleAdd([]string{"."})
+	index, _ := vcs.ReadIndex()
+	if _, ok := index["src/vendor/x.md"]; !ok {
+		t.Fatalf("add should keep files outside the sparse checkout staged")
+	}
+}
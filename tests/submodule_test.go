@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestSubmodules(t *testing.T) {
+	lib := setupRemote(t)
+	if err := os.Chdir(lib); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	libFirst := headHash(t)
+	libSecond := commitFiles(t, lib, "second", map[string]string{"a.txt": "a2"})
+
+	super := setupRepo(t)
+	commitFiles(t, super, "first", map[string]string{"main.txt": "main"})
+
+	if err := vcs.AddSubmodule(lib, "vendor/lib"); err != nil {
+		t.Fatalf("submodule add failed: %v", err)
+	}
+	if !strings.Contains(readFile(t, super, ".gtmodules"), "url = "+lib) {
+		t.Fatalf("unexpected .gtmodules:\n%s", readFile(t, super, ".gtmodules"))
+	}
+	index, _ := vcs.ReadIndex()
+	if entry := index["vendor/lib"]; entry.Mode != vcs.GitlinkMode || entry.Hash != libSecond {
+		t.Fatalf("the gitlink should be staged, got %+v", entry)
+	}
+
+	// Commits record the submodule's commit, not its files.
+	vcs.HandleCommit("add lib", super)
+	files, _ := vcs.FlattenCommit(headHash(t))
+	if files["vendor/lib"].Hash != libSecond || files["vendor/lib/a.txt"].Hash != "" {
+		t.Fatalf("unexpected committed files: %v", files)
+	}
+
+	// Moving the submodule shows up as a change until it is updated.
+	if err := os.Chdir(filepath.Join(super, "vendor", "lib")); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	vcs.HandleCheckout(".", libFirst)
+	if err := os.Chdir(super); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	status, _ := vcs.GetStatus()
+	if len(status.Unstaged) != 1 || status.Unstaged[0].Path != "vendor/lib" {
+		t.Fatalf("expected vendor/lib to be modified, got %+v", status)
+	}
+
+	updated, err := vcs.UpdateSubmodules()
+	if err != nil || len(updated) != 1 {
+		t.Fatalf("submodule update failed: %v, %+v", err, updated)
+	}
+	if readFile(t, super, "vendor/lib/a.txt") != "a2" {
+		t.Fatalf("update should check out the recorded commit")
+	}
+
+	// A clone leaves an empty directory until the submodule is updated.
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if err := vcs.Clone(super, "clone", vcs.FetchOptions{}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	clone, _ := filepath.Abs("clone")
+	if err := os.Chdir(clone); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	submodules, err := vcs.ReadSubmodules()
+	if err != nil || len(submodules) != 1 || submodules[0].Current != "" || submodules[0].Recorded != libSecond {
+		t.Fatalf("unexpected submodules in the clone: %+v, %v", submodules, err)
+	}
+	if _, err := vcs.UpdateSubmodules(); err != nil {
+		t.Fatalf("submodule update in the clone failed: %v", err)
+	}
+	if readFile(t, clone, "vendor/lib/a.txt") != "a2" {
+		t.Fatalf("update should clone the submodule")
+	}
+}
+
+func TestRebaseWithSubmodule(t *testing.T) {
+	lib := setupRemote(t)
+
+	super := setupRepo(t)
+	commitFiles(t, super, "base", map[string]string{"f.txt": "base\n"})
+	if err := vcs.AddSubmodule(lib, "lib"); err != nil {
+		t.Fatalf("submodule add failed: %v", err)
+	}
+	vcs.HandleCommit("add lib", super)
+
+	vcs.CreateBranch("feature", "HEAD", false)
+	vcs.HandleCheckout(super, "feature")
+	commitFiles(t, super, "one", map[string]string{"one.txt": "1\n"})
+	vcs.HandleCheckout(super, "main")
+	commitFiles(t, super, "main", map[string]string{"m.txt": "m\n"})
+	vcs.HandleCheckout(super, "feature")
+
+	// The submodule directory must not count as a local change.
+	if err := vcs.StartRebase("main", "", "", ""); err != nil {
+		t.Fatalf("rebase with a submodule present failed: %v", err)
+	}
+	if got := strings.Join(messages(t), ","); got != "one,main,add lib,base" {
+		t.Fatalf("unexpected history after rebase: %s", got)
+	}
+	if readFile(t, super, "lib/a.txt") == "" {
+		t.Fatalf("the submodule checkout should be left alone")
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestLightweightTag(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"file.txt": "v1"})
+	first := headHash(t)
+
+	if _, err := vcs.CreateTag("v1.0", "HEAD", false, "", false); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+
+	hash, err := vcs.ReadRef("refs/tags/v1.0")
+	if err != nil || hash != first {
+		t.Fatalf("refs/tags/v1.0 = %q (%v), want %s", hash, err, first)
+	}
+
+	commitFiles(t, tmp, "second", map[string]string{"file.txt": "v2"})
+
+	resolved, err := vcs.ResolveRevision("v1.0")
+	if err != nil || resolved != first {
+		t.Fatalf("v1.0 resolved to %q (%v), want %s", resolved, err, first)
+	}
+
+	if _, err := vcs.CreateTag("v1.0", "HEAD", false, "", false); err == nil {
+		t.Fatalf("expected an error when re-creating an existing tag")
+	}
+}
+
+func TestAnnotatedTag(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "release", map[string]string{"file.txt": "content"})
+	commit := headHash(t)
+
+	t.Setenv("GT_AUTHOR_NAME", "Tester")
+	t.Setenv("GT_AUTHOR_EMAIL", "tester@example.com")
+
+	tagHash, err := vcs.CreateTag("v2.0", "HEAD", true, "Release 2.0\n\nNotes", false)
+	if err != nil {
+		t.Fatalf("failed to create annotated tag: %v", err)
+	}
+	if tagHash == commit {
+		t.Fatalf("annotated tag ref should point at a tag object")
+	}
+
+	objType, data, err := vcs.ReadObjectType(tagHash)
+	if err != nil || objType != "tag" {
+		t.Fatalf("tag object type = %q (%v), want tag", objType, err)
+	}
+
+	tag := vcs.ParseTag(string(data))
+	if tag.Object != commit || tag.Type != "commit" || tag.Name != "v2.0" {
+		t.Fatalf("unexpected tag object: %+v", tag)
+	}
+	if tag.Tagger != "Tester <tester@example.com>" {
+		t.Fatalf("tagger = %q", tag.Tagger)
+	}
+	if tag.Message != "Release 2.0\n\nNotes" {
+		t.Fatalf("message = %q", tag.Message)
+	}
+
+	resolved, err := vcs.ResolveRevision("v2.0")
+	if err != nil || resolved != commit {
+		t.Fatalf("v2.0 resolved to %q (%v), want %s", resolved, err, commit)
+	}
+	object, err := vcs.ResolveObject("tags/v2.0")
+	if err != nil || object != tagHash {
+		t.Fatalf("tags/v2.0 resolved to %q (%v), want %s", object, err, tagHash)
+	}
+}
+
+func TestRefNamesStayInsideRefs(t *testing.T) {
+	tmp := setupRepo(t)
+	commitFiles(t, tmp, "first", map[string]string{"file.txt": "content"})
+	head := headHash(t)
+
+	vcs.HandleTagDelete("../../HEAD")
+	if headHash(t) != head {
+		t.Fatalf("deleting a tag must not reach outside refs/tags")
+	}
+
+	writeFile(t, tmp, "secret", "not a ref")
+	if _, err := vcs.ResolveObject("../../secret"); err == nil {
+		t.Fatalf("a revision must not be read from outside refs/")
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoTrack/vcs"
+)
+
+func TestWorktrees(t *testing.T) {
+	tmp := setupRepo(t)
+	first := commitFiles(t, tmp, "first", map[string]string{"a.txt": "a"})
+	vcs.CreateBranch("feature", "HEAD", false)
+
+	path := filepath.Join(t.TempDir(), "feature-tree")
+	worktree, err := vcs.AddWorktree(path, "feature")
+	if err != nil {
+		t.Fatalf("worktree add failed: %v", err)
+	}
+	if readFile(t, path, "a.txt") != "a" {
+		t.Fatalf("worktree add should check out the files")
+	}
+	pointer := readFile(t, path, ".gt")
+	if !strings.HasPrefix(pointer, "gtdir: ") || !strings.Contains(pointer, filepath.Join(".gt", "worktrees", worktree.Name)) {
+		t.Fatalf("unexpected .gt pointer file: %q", pointer)
+	}
+
+	// The same branch cannot be checked out twice.
+	if _, err := vcs.AddWorktree(filepath.Join(t.TempDir(), "again"), "feature"); err == nil {
+		t.Fatalf("adding a second worktree for feature should fail")
+	}
+	if _, err := vcs.AddWorktree(filepath.Join(t.TempDir(), "main"), "main"); err == nil {
+		t.Fatalf("main is checked out in the main worktree")
+	}
+	if err := vcs.SetHead("refs/heads/feature", "checkout"); err == nil {
+		t.Fatalf("checking out feature in the main worktree should fail")
+	}
+
+	// Commits in the worktree move the shared branch, not the main HEAD.
+	if err := os.Chdir(path); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	second := commitFiles(t, path, "second", map[string]string{"b.txt": "b"})
+	index, _ := vcs.ReadIndex()
+	if _, ok := index["b.txt"]; !ok {
+		t.Fatalf("the worktree should have its own index")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("the main working tree should not change")
+	}
+
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if feature, _ := vcs.ReadRef("refs/heads/feature"); feature != second {
+		t.Fatalf("feature should point at the worktree commit")
+	}
+	if headHash(t) != first {
+		t.Fatalf("the main HEAD should not move")
+	}
+
+	worktrees, err := vcs.ListWorktrees()
+	if err != nil || len(worktrees) != 2 || worktrees[1].Path != path || worktrees[1].Head != "refs/heads/feature" {
+		t.Fatalf("unexpected worktree list: %+v, %v", worktrees, err)
+	}
+
+	// Uncommitted changes keep the worktree unless forced.
+	writeFile(t, path, "dirty.txt", "dirty")
+	if err := vcs.RemoveWorktree(path, false); err == nil {
+		t.Fatalf("removing a dirty worktree should fail")
+	}
+	if err := vcs.RemoveWorktree(path, true); err != nil {
+		t.Fatalf("worktree remove failed: %v", err)
+	}
+	if fileExists(path) {
+		t.Fatalf("worktree directory should be gone")
+	}
+	if err := vcs.SetHead("refs/heads/feature", "checkout"); err != nil {
+		t.Fatalf("feature is free again after the worktree is removed: %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// bisectState is what .gt/bisect holds while a bisection is in progress.
+type bisectState struct {
+	Start string   // Branch ref or commit checked out before bisecting
+	Bad   string   // Newest commit known to be bad, "" until marked
+	Good  []string // Commits known to be good
+	Skip  []string // Commits that cannot be tested
+	Log   []string // The commands so far, with comments naming the commits
+}
+
+// BisectStep is where a bisection stands after a command.
+type BisectStep struct {
+	Next       string   // Commit checked out for testing, "" when there is none
+	Remaining  int      // Commits that may still need testing after Next
+	Steps      int      // Rough number of tests left after Next
+	FirstBad   string   // The first bad commit, once it is found
+	Candidates []string // When only skipped commits are left, where the first bad one may be
+	Status     string   // What the bisection waits for before it can start
+}
+
+func bisectInProgress() bool {
+	_, err := os.Stat(repoPath(constants.BisectDir))
+	return err == nil
+}
+
+func readBisect() (bisectState, error) {
+	if !bisectInProgress() {
+		return bisectState{}, fmt.Errorf("not bisecting; run 'gt bisect start' first")
+	}
+
+	files := make(map[string]string)
+	for _, name := range []string{"start", "bad", "good", "skip", "log"} {
+		data, err := os.ReadFile(repoPath(filepath.Join(constants.BisectDir, name)))
+		if err != nil && !os.IsNotExist(err) {
+			return bisectState{}, err
+		}
+		files[name] = string(data)
+	}
+
+	return bisectState{
+		Start: strings.TrimSpace(files["start"]),
+		Bad:   strings.TrimSpace(files["bad"]),
+		Good:  nonEmptyLines(files["good"]),
+		Skip:  nonEmptyLines(files["skip"]),
+		Log:   nonEmptyLines(files["log"]),
+	}, nil
+}
+
+func writeBisect(state bisectState) error {
+	if err := os.MkdirAll(repoPath(constants.BisectDir), 0755); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"start": state.Start + "\n",
+		"bad":   state.Bad + "\n",
+		"good":  joinLines(state.Good),
+		"skip":  joinLines(state.Skip),
+		"log":   joinLines(state.Log),
+	}
+	for name, content := range files {
+		if err := writeFileAtomic(filepath.Join(constants.BisectDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartBisect begins a bisection from the current HEAD. The bad and good
+// revisions are optional and can be marked later.
+func StartBisect(bad string, goods []string) (BisectStep, error) {
+	if bisectInProgress() {
+		return BisectStep{}, fmt.Errorf("already bisecting; run 'gt bisect reset' first")
+	}
+	if err := requireCleanTree(); err != nil {
+		return BisectStep{}, err
+	}
+
+	symref, hash, err := ReadHead()
+	if err != nil {
+		return BisectStep{}, err
+	}
+	state := bisectState{Start: symref, Log: []string{"gt bisect start"}}
+	if symref == "" {
+		state.Start = hash
+	}
+
+	if bad != "" {
+		if err := markBisect(&state, "bad", bad); err != nil {
+			return BisectStep{}, err
+		}
+	}
+	for _, good := range goods {
+		if err := markBisect(&state, "good", good); err != nil {
+			return BisectStep{}, err
+		}
+	}
+
+	if err := writeBisect(state); err != nil {
+		return BisectStep{}, err
+	}
+	return bisectNext(state)
+}
+
+// MarkBisect records revs, HEAD when there are none, as "good", "bad" or
+// "skip" and checks out the next commit to test.
+func MarkBisect(kind string, revs []string) (BisectStep, error) {
+	state, err := readBisect()
+	if err != nil {
+		return BisectStep{}, err
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+
+	for _, rev := range revs {
+		if err := markBisect(&state, kind, rev); err != nil {
+			return BisectStep{}, err
+		}
+	}
+
+	if err := writeBisect(state); err != nil {
+		return BisectStep{}, err
+	}
+	return bisectNext(state)
+}
+
+func markBisect(state *bisectState, kind string, rev string) error {
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	commit, err := ReadCommit(hash)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "bad":
+		state.Bad = hash
+	case "good":
+		state.Good = append(state.Good, hash)
+	case "skip":
+		state.Skip = append(state.Skip, hash)
+	default:
+		return fmt.Errorf("unknown bisect term '%s'", kind)
+	}
+
+	summary, _, _ := strings.Cut(commit.Message, "\n")
+	state.Log = append(state.Log, fmt.Sprintf("# %s: [%s] %s", kind, hash, summary), "gt bisect "+kind+" "+hash)
+	return nil
+}
+
+// bisectNext narrows the range down to the commits reachable from the bad
+// one but not from any good one, and checks out the untested commit closest
+// to its middle.
+func bisectNext(state bisectState) (BisectStep, error) {
+	switch {
+	case state.Bad == "" && len(state.Good) == 0:
+		return BisectStep{Status: "waiting for both good and bad commits"}, nil
+	case state.Bad == "":
+		return BisectStep{Status: fmt.Sprintf("waiting for bad commit, %d good commit(s) known", len(state.Good))}, nil
+	case len(state.Good) == 0:
+		return BisectStep{Status: "waiting for good commit(s), bad commit known"}, nil
+	}
+
+	good := make(map[string]bool)
+	for _, hash := range state.Good {
+		ancestors, err := Ancestors(hash)
+		if err != nil {
+			return BisectStep{}, err
+		}
+		for ancestor := range ancestors {
+			good[ancestor] = true
+		}
+	}
+	if good[state.Bad] {
+		return BisectStep{}, fmt.Errorf("the bad commit %s is an ancestor of a good commit; were good and bad swapped?", state.Bad)
+	}
+
+	history, err := RevList(state.Bad, "")
+	if err != nil {
+		return BisectStep{}, err
+	}
+	// Newest first, with the bad commit at 0.
+	var candidates []string
+	for _, hash := range history {
+		if good[hash] {
+			break
+		}
+		candidates = append(candidates, hash)
+	}
+
+	skipped := make(map[string]bool)
+	for _, hash := range state.Skip {
+		skipped[hash] = true
+	}
+
+	if len(candidates) == 1 {
+		return finishBisect(state, state.Bad)
+	}
+
+	// Pick the testable commit nearest to the middle, so that either answer
+	// rules out about half of the candidates.
+	middle := len(candidates) / 2
+	pick := -1
+	for distance := 0; distance < len(candidates) && pick == -1; distance++ {
+		for _, i := range []int{middle + distance, middle - distance} {
+			if i > 0 && i < len(candidates) && !skipped[candidates[i]] {
+				pick = i
+				break
+			}
+		}
+	}
+	if pick == -1 {
+		return BisectStep{Candidates: candidates}, nil
+	}
+
+	// A good answer keeps the commits newer than pick, a bad one those from
+	// pick on; either way the bad end is already known.
+	remaining := max(pick-1, len(candidates)-pick-1)
+	step := BisectStep{Next: candidates[pick], Remaining: remaining, Steps: bits.Len(uint(remaining))}
+	if err := bisectCheckout(step.Next); err != nil {
+		return BisectStep{}, err
+	}
+	return step, nil
+}
+
+func finishBisect(state bisectState, firstBad string) (BisectStep, error) {
+	commit, err := ReadCommit(firstBad)
+	if err != nil {
+		return BisectStep{}, err
+	}
+	summary, _, _ := strings.Cut(commit.Message, "\n")
+
+	line := fmt.Sprintf("# first bad commit: [%s] %s", firstBad, summary)
+	if state.Log[len(state.Log)-1] != line {
+		state.Log = append(state.Log, line)
+		if err := writeBisect(state); err != nil {
+			return BisectStep{}, err
+		}
+	}
+	return BisectStep{FirstBad: firstBad}, nil
+}
+
+// bisectCheckout detaches HEAD at target, refusing to touch local changes.
+func bisectCheckout(target string) error {
+	symref, hash, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	if symref == "" && hash == target {
+		return nil
+	}
+	if err := requireCleanTree(); err != nil {
+		return err
+	}
+
+	from, err := headName()
+	if err != nil {
+		return err
+	}
+	return checkoutCommit(target, fmt.Sprintf("checkout: moving from %s to %s", from, target))
+}
+
+// ResetBisect ends the bisection and checks out what HEAD was on before it
+// started.
+func ResetBisect() error {
+	state, err := readBisect()
+	if err != nil {
+		return err
+	}
+
+	symref, hash, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	if symref != state.Start && hash != state.Start {
+		if err := requireCleanTree(); err != nil {
+			return err
+		}
+		from, err := headName()
+		if err != nil {
+			return err
+		}
+		to := strings.TrimPrefix(state.Start, "refs/heads/")
+		if err := checkoutCommit(state.Start, fmt.Sprintf("checkout: moving from %s to %s", from, to)); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(repoPath(constants.BisectDir))
+}
+
+// BisectLog returns the commands of the bisection so far.
+func BisectLog() ([]string, error) {
+	state, err := readBisect()
+	if err != nil {
+		return nil, err
+	}
+	return state.Log, nil
+}
+
+// RunBisect tests the checked out commit with command until the first bad
+// commit is found. The command is run directly, not through a shell, so its
+// arguments reach it as given. An exit code of 0 marks the commit good, 125 skips it
+// and anything else up to 127 marks it bad; higher codes stop the run.
+// onStep is called with every intermediate step.
+func RunBisect(command []string, onStep func(BisectStep)) (BisectStep, error) {
+	state, err := readBisect()
+	if err != nil {
+		return BisectStep{}, err
+	}
+	if state.Bad == "" || len(state.Good) == 0 {
+		return BisectStep{}, fmt.Errorf("bisect run needs a good and a bad commit")
+	}
+
+	script := strings.Join(command, " ")
+	for {
+		fmt.Println("running", script)
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+		kind := "good"
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return BisectStep{}, fmt.Errorf("bisect run failed: %v", err)
+			}
+			switch code := exitErr.ExitCode(); {
+			case code == 125:
+				kind = "skip"
+			case code > 0 && code < 128:
+				kind = "bad"
+			default:
+				return BisectStep{}, fmt.Errorf("bisect run failed: exit code %d from '%s' is < 0 or >= 128", code, script)
+			}
+		}
+
+		step, err := MarkBisect(kind, nil)
+		if err != nil {
+			return BisectStep{}, err
+		}
+		if step.Next == "" {
+			return step, nil
+		}
+		onStep(step)
+	}
+}
@@ -0,0 +1,157 @@
+package vcs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BlameLine is one line of a file together with the commit that last
+// changed it.
+type BlameLine struct {
+	Commit   Commit
+	Boundary bool   // The commit has no parent to look further back into
+	OrigLine int    // Line number in the commit's version of the file
+	Line     int    // Line number in the blamed revision
+	Text     string // The line without its "\n"
+}
+
+// Blame attributes every line of path at rev to the commit that introduced
+// it. It walks the parents and diffs each version of the file against the
+// one before; lines a commit added are its own, the rest are followed
+// further back. History beyond a shallow boundary is not searched.
+func Blame(path string, rev string) ([]BlameLine, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	path = cleanPathspec(path)
+
+	commit, err := ReadCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+	entry, found, err := FindTreeEntry(commit.TreeHash, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found || entry.Type != "blob" {
+		return nil, fmt.Errorf("no such path '%s' in %s", path, rev)
+	}
+	content, err := ReadObject(entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := SplitLines(content)
+	result := make([]BlameLine, len(lines))
+	// pending maps each line still to be attributed to its index in the
+	// version of the file held by commit.
+	pending := make(map[int]int)
+	for i, line := range lines {
+		result[i] = BlameLine{Line: i + 1, Text: strings.TrimSuffix(line, "\n")}
+		pending[i] = i
+	}
+
+	shallow, err := ReadShallow()
+	if err != nil {
+		return nil, err
+	}
+
+	for len(pending) > 0 {
+		parentHash := parentOf(commit, shallow)
+
+		var parent Commit
+		var parentEntry TreeEntry
+		inParent := false
+		if parentHash != "" {
+			if parent, err = ReadCommit(parentHash); err != nil {
+				return nil, err
+			}
+			if parentEntry, inParent, err = FindTreeEntry(parent.TreeHash, path); err != nil {
+				return nil, err
+			}
+			inParent = inParent && parentEntry.Type == "blob"
+		}
+
+		if !inParent {
+			// Whatever is left was added by this commit.
+			for final, current := range pending {
+				result[final].Commit = commit
+				result[final].Boundary = parentHash == ""
+				result[final].OrigLine = current + 1
+			}
+			break
+		}
+
+		if parentEntry.Hash != entry.Hash {
+			parentContent, err := ReadObject(parentEntry.Hash)
+			if err != nil {
+				return nil, err
+			}
+			parentLines := SplitLines(parentContent)
+			matches := matchLines(lines, parentLines)
+
+			next := make(map[int]int)
+			for final, current := range pending {
+				if matches[current] == -1 {
+					result[final].Commit = commit
+					result[final].OrigLine = current + 1
+					continue
+				}
+				next[final] = matches[current]
+			}
+			pending, lines = next, parentLines
+		}
+
+		commit, entry = parent, parentEntry
+	}
+
+	return result, nil
+}
+
+// ParseLineRange reads a -L argument of the form "start,end", "start," or
+// "start,+count" against a file of total lines and returns the 1-based
+// inclusive bounds.
+func ParseLineRange(spec string, total int) (int, int, error) {
+	startSpec, endSpec, _ := strings.Cut(spec, ",")
+
+	start, err := strconv.Atoi(startSpec)
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid line range '%s'", spec)
+	}
+
+	end := total
+	switch {
+	case strings.HasPrefix(endSpec, "+"):
+		count, err := strconv.Atoi(endSpec[1:])
+		if err != nil || count < 1 {
+			return 0, 0, fmt.Errorf("invalid line range '%s'", spec)
+		}
+		end = start + count - 1
+	case endSpec != "":
+		if end, err = strconv.Atoi(endSpec); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid line range '%s'", spec)
+		}
+	}
+
+	if start > total {
+		return 0, 0, fmt.Errorf("file has only %d lines", total)
+	}
+	if end > total {
+		end = total
+	}
+	return start, end, nil
+}
+
+// splitAuthor splits "Name <email>" into its name and email.
+func splitAuthor(author string) (string, string) {
+	name, email, ok := strings.Cut(author, " <")
+	if !ok {
+		return author, ""
+	}
+	return name, strings.TrimSuffix(email, ">")
+}
@@ -0,0 +1,81 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CurrentBranch returns the short name of the checked out branch, or "" when
+// HEAD is detached.
+func CurrentBranch() (string, error) {
+	symref, _, err := ReadHead()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(symref, "refs/heads/"), nil
+}
+
+func BranchExists(name string) bool {
+	hash, err := ReadRef("refs/heads/" + name)
+	return err == nil && hash != ""
+}
+
+func CreateBranch(name string, rev string, force bool) (string, error) {
+	if err := CheckRefName(name); err != nil {
+		return "", err
+	}
+
+	if BranchExists(name) && !force {
+		return "", fmt.Errorf("a branch named '%s' already exists", name)
+	}
+
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return "", err
+	}
+
+	if err := UpdateRef("refs/heads/"+name, hash, "branch: Created from "+rev); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func DeleteBranch(name string) (string, error) {
+	if err := CheckRefName(name); err != nil {
+		return "", err
+	}
+
+	current, err := CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	if current == name {
+		return "", fmt.Errorf("cannot delete the checked out branch '%s'", name)
+	}
+	other, err := checkedOutElsewhere(repoPath(constants.GTDir), "refs/heads/"+name, "")
+	if err != nil {
+		return "", err
+	}
+	if other != "" {
+		return "", fmt.Errorf("cannot delete branch '%s' checked out at '%s'", name, other)
+	}
+
+	hash, err := ReadRef("refs/heads/" + name)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", fmt.Errorf("branch '%s' not found", name)
+	}
+
+	if err := DeleteRef("refs/heads/" + name); err != nil {
+		return "", err
+	}
+	os.Remove(repoPath(filepath.Join(constants.LogsDir, "refs/heads", name)))
+
+	return hash, nil
+}
@@ -0,0 +1,322 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A bundle carries refs and the objects they need in a single file:
+//
+//	# gt bundle v1
+//	-<hash> <subject>   commit the receiver must already have (incremental bundles)
+//	<hash> <ref>        ref stored in the bundle
+//	(blank line)
+//	<pack>
+const bundleSignature = "# gt bundle v1"
+
+// Bundle is the header of a bundle file.
+type Bundle struct {
+	Prerequisites []string          // Commits the pack builds on
+	Refs          map[string]string // Full ref name (or "HEAD") to hash
+}
+
+// CreateBundle writes a bundle of the given revisions to file. A revision is
+// a ref, branch or tag name, "HEAD", "--all" for every branch and tag,
+// "^<rev>" to leave out what rev already has, or "<a>..<b>" for b without a.
+// It returns the number of objects written.
+func CreateBundle(file string, revs []string) (int, error) {
+	bundle := Bundle{Refs: make(map[string]string)}
+	var excludes []string
+
+	add := func(rev string) error {
+		ref, hash, err := bundleRef(rev)
+		if err != nil {
+			return err
+		}
+		bundle.Refs[ref] = hash
+		return nil
+	}
+
+	for _, rev := range revs {
+		switch {
+		case rev == "--all":
+			refs, err := filterRefs()
+			if err != nil {
+				return 0, err
+			}
+			for _, ref := range refs {
+				if err := add(ref); err != nil {
+					return 0, err
+				}
+			}
+		case strings.HasPrefix(rev, "^"):
+			excludes = append(excludes, rev[1:])
+		case strings.Contains(rev, ".."):
+			from, to, _ := strings.Cut(rev, "..")
+			excludes = append(excludes, from)
+			if err := add(to); err != nil {
+				return 0, err
+			}
+		default:
+			if err := add(rev); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(bundle.Refs) == 0 {
+		return 0, fmt.Errorf("refusing to create an empty bundle")
+	}
+
+	for _, rev := range excludes {
+		hash, err := ResolveRevision(rev)
+		if err != nil {
+			return 0, err
+		}
+		bundle.Prerequisites = append(bundle.Prerequisites, hash)
+	}
+
+	var wants []string
+	for _, hash := range bundle.Refs {
+		wants = append(wants, hash)
+	}
+	objects, err := packObjects(constants.ObjectsDir, wants, bundle.Prerequisites, FetchOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, bundleSignature)
+	for _, hash := range bundle.Prerequisites {
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(w, "-%s %s\n", hash, firstLine(commit.Message))
+	}
+	for _, ref := range sortedKeys(bundle.Refs) {
+		fmt.Fprintf(w, "%s %s\n", bundle.Refs[ref], ref)
+	}
+	fmt.Fprintln(w)
+
+	if err := writePack(w, constants.ObjectsDir, objects); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return len(objects), out.Sync()
+}
+
+// bundleRef turns a revision given to CreateBundle into the full ref name
+// recorded in the bundle.
+func bundleRef(rev string) (string, string, error) {
+	if rev == "HEAD" {
+		hash, err := ResolveObject(rev)
+		return "HEAD", hash, err
+	}
+
+	for _, ref := range []string{rev, "refs/heads/" + rev, "refs/tags/" + rev} {
+		if !strings.HasPrefix(ref, "refs/") {
+			continue
+		}
+		hash, err := ReadRef(ref)
+		if err != nil {
+			return "", "", err
+		}
+		if hash != "" {
+			return ref, hash, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("'%s' is not a branch or tag; a bundle can only store refs", rev)
+}
+
+// isBundle reports whether path is a file starting with the bundle signature.
+func isBundle(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	return strings.TrimSuffix(line, "\n") == bundleSignature
+}
+
+// openBundle reads the header of a bundle file and leaves the returned
+// reader at the start of the pack.
+func openBundle(path string) (Bundle, *os.File, *bufio.Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Bundle{}, nil, nil, err
+	}
+
+	in := bufio.NewReader(file)
+	bundle := Bundle{Refs: make(map[string]string)}
+
+	fail := func(format string, args ...any) (Bundle, *os.File, *bufio.Reader, error) {
+		file.Close()
+		return Bundle{}, nil, nil, fmt.Errorf(format, args...)
+	}
+
+	first, err := in.ReadString('\n')
+	if err != nil || strings.TrimSuffix(first, "\n") != bundleSignature {
+		return fail("'%s' is not a bundle", path)
+	}
+
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return fail("truncated bundle header in '%s'", path)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+
+		if rest, ok := strings.CutPrefix(line, "-"); ok {
+			hash, _, _ := strings.Cut(rest, " ")
+			bundle.Prerequisites = append(bundle.Prerequisites, hash)
+			continue
+		}
+
+		hash, ref, ok := strings.Cut(line, " ")
+		if !ok || !isHex(hash) {
+			return fail("bad bundle header line: %q", line)
+		}
+		// Ref names become paths when the bundle is fetched, so an
+		// invalid one fails the whole bundle.
+		if ref != "HEAD" {
+			name, isRef := strings.CutPrefix(ref, "refs/")
+			if !isRef || CheckRefName(name) != nil {
+				return fail("bad ref name in bundle header: %q", ref)
+			}
+		}
+		bundle.Refs[ref] = hash
+	}
+
+	return bundle, file, in, nil
+}
+
+// VerifyBundle checks that the bundle is intact and that this repository
+// has the commits it builds on, without storing anything.
+func VerifyBundle(path string) (Bundle, error) {
+	bundle, file, in, err := openBundle(path)
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer file.Close()
+
+	if err := checkPrerequisites(bundle); err != nil {
+		return bundle, err
+	}
+
+	return bundle, checkBundlePack(bundle, in, "")
+}
+
+// Unbundle stores the objects of a bundle in this repository and returns
+// its header. Refs are left alone; fetch from the bundle to update them.
+func Unbundle(path string) (Bundle, error) {
+	bundle, file, in, err := openBundle(path)
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer file.Close()
+
+	if err := checkPrerequisites(bundle); err != nil {
+		return bundle, err
+	}
+
+	return bundle, checkBundlePack(bundle, in, constants.ObjectsDir)
+}
+
+func checkPrerequisites(bundle Bundle) error {
+	var missing []string
+	for _, hash := range bundle.Prerequisites {
+		if !ObjectExists(hash) {
+			missing = append(missing, hash)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("repository lacks the prerequisite commits: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkBundlePack reads the pack into objectsDir ("" to only verify it) and
+// makes sure every ref in the bundle points at an object that is there.
+func checkBundlePack(bundle Bundle, in io.Reader, objectsDir string) error {
+	hashes, err := readPack(in, objectsDir)
+	if err != nil {
+		return err
+	}
+
+	inPack := make(map[string]bool)
+	for _, hash := range hashes {
+		inPack[hash] = true
+	}
+	for ref, hash := range bundle.Refs {
+		if !inPack[hash] && !ObjectExists(hash) {
+			return fmt.Errorf("bundle ref %s points at missing object %s", ref, hash)
+		}
+	}
+	return nil
+}
+
+// bundleTransport lets clone and fetch read from a bundle file.
+type bundleTransport struct {
+	path string
+}
+
+func (t *bundleTransport) listRefs() (map[string]string, string, error) {
+	bundle, file, _, err := openBundle(t.path)
+	if err != nil {
+		return nil, "", err
+	}
+	file.Close()
+
+	refs := make(map[string]string)
+	for ref, hash := range bundle.Refs {
+		if strings.HasPrefix(ref, "refs/heads/") || strings.HasPrefix(ref, "refs/tags/") {
+			refs[ref] = hash
+		}
+	}
+
+	// Bundles store HEAD as a hash; guess the branch it was on.
+	head := ""
+	for _, ref := range sortedKeys(refs) {
+		if !strings.HasPrefix(ref, "refs/heads/") {
+			continue
+		}
+		if hash, ok := bundle.Refs["HEAD"]; ok && refs[ref] != hash {
+			continue
+		}
+		if head == "" || ref == "refs/heads/"+constants.DefaultBranch {
+			head = ref
+		}
+	}
+
+	return refs, head, nil
+}
+
+func (t *bundleTransport) fetchObjects(wants []string, haves []string, opts FetchOptions) error {
+	if opts != (FetchOptions{}) {
+		return fmt.Errorf("bundles hold the whole history; --depth and --filter are not supported")
+	}
+	_, err := Unbundle(t.path)
+	return err
+}
+
+func (t *bundleTransport) push(update RefUpdate, haves []string) error {
+	return fmt.Errorf("cannot push to a bundle; create a new one with gt bundle create")
+}
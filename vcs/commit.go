@@ -15,73 +15,64 @@ import (
 type Commit struct {
 	TreeHash   string
 	ParentHash string
+	Author     string // "Name <email>"
 	TimeStamp  int64
 	Message    string // Commit message
 	Hash       string // Commit hash
 
 }
 
-func WriteCommit(treeHash, parentHash, message string, objectsDir string) Commit {
-	timestamp := time.Now().Unix()
+func WriteCommit(treeHash, parentHash, author, message string, objectsDir string) Commit {
+	return writeCommitObject(Commit{
+		TreeHash:   treeHash,
+		ParentHash: parentHash,
+		Author:     author,
+		TimeStamp:  time.Now().Unix(),
+		Message:    message,
+	}, objectsDir)
+}
 
+// writeCommitObject stores commit as is, keeping its timestamp, and returns
+// it with Hash set. Writing the same fields twice gives the same hash.
+func writeCommitObject(commit Commit, objectsDir string) Commit {
 	// Construct the commit content in binary format
 	var commitData []byte
 
 	// Add tree hash
-	commitData = append(commitData, []byte(fmt.Sprintf("tree %s\n", treeHash))...)
+	commitData = append(commitData, []byte(fmt.Sprintf("tree %s\n", commit.TreeHash))...)
 
 	// Add parent hash (if there's a parent)
-	if parentHash != "" {
-		commitData = append(commitData, []byte(fmt.Sprintf("parent %s\n", parentHash))...)
+	if commit.ParentHash != "" {
+		commitData = append(commitData, []byte(fmt.Sprintf("parent %s\n", commit.ParentHash))...)
+	}
+
+	// Add author (if known)
+	if commit.Author != "" {
+		commitData = append(commitData, []byte(fmt.Sprintf("author %s\n", commit.Author))...)
 	}
 
 	// Add timestamp
-	commitData = append(commitData, []byte(fmt.Sprintf("timestamp %d\n", timestamp))...)
+	commitData = append(commitData, []byte(fmt.Sprintf("timestamp %d\n", commit.TimeStamp))...)
 
 	// Add commit message (ensure the message is properly encoded in binary)
-	commitData = append(commitData, []byte(fmt.Sprintf("message %s\n", message))...)
+	commitData = append(commitData, []byte(fmt.Sprintf("message %s\n", commit.Message))...)
 
 	// Create the final commit content by including the header: "commit <size>\0"
 	commitContent := append([]byte(fmt.Sprintf("commit %d\000", len(commitData))), commitData...)
 
 	// Compute the hash of the commit content
-	commitHash := HashContent(commitContent)
-
-	// Create the full object path based on the hash
-	commitPath := filepath.Join(objectsDir, commitHash[:2], commitHash[2:])
-
-	// Create the necessary directories for the object path
-	if err := os.MkdirAll(filepath.Dir(commitPath), 0755); err != nil {
-		log.Fatal(err) // Handle error appropriately in your code
-	}
+	commit.Hash = HashContent(commitContent)
 
-	if err := os.WriteFile(commitPath, commitContent, 0644); err != nil {
-		// Write the commit content to the object file in binary format
+	if err := writeObjectFile(commit.Hash, commitContent, objectsDir); err != nil {
 		log.Fatal(err) // Handle error appropriately in your code
 	}
 
-	// Return the commit object with the computed hash and content
-	return Commit{
-		TreeHash:   treeHash,
-		ParentHash: parentHash,
-		TimeStamp:  timestamp,
-		Message:    message,
-		Hash:       commitHash,
-	}
+	return commit
 }
 
 func GetLatestCommitHash() (string, error) {
-	headPath := filepath.Join(constants.GTDir, "HEAD")
-
-	data, err := os.ReadFile(headPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil // No commits yet
-		}
-		return "", err
-	}
-
-	return strings.TrimSpace(string(data)), nil
+	_, hash, err := ReadHead()
+	return hash, err
 }
 
 func GetCurrentCommitHash(GTDir string) (string, error) {
@@ -98,21 +89,19 @@ func GetCurrentCommitHash(GTDir string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
-func UpdateCurrentCommit(GTDir string, commitHash string) error {
-	headPath := filepath.Join(GTDir, "HEAD")
-	return os.WriteFile(headPath, []byte(commitHash+"\n"), 0644)
-}
-
-func UpdateLatestCommit(GTDir string, commitHash string) error {
-	headPath := filepath.Join(GTDir, "HEAD")
-	return os.WriteFile(headPath, []byte(commitHash+"\n"), 0644)
+// UpdateLatestCommit moves HEAD, or the branch HEAD points at, to commitHash
+// and records the move in the reflog.
+func UpdateLatestCommit(commitHash string, reason string) error {
+	return UpdateRef("HEAD", commitHash, reason)
 }
 
 func ParseCommit(data string) Commit {
-	lines := strings.Split(data, "\n") // Split into lines
 	commit := Commit{}
 
-	for _, line := range lines {
+	for len(data) > 0 {
+		line, rest, _ := strings.Cut(data, "\n")
+		data = rest
+
 		parts := strings.SplitN(line, " ", 2) // Split each line into key-value pair
 		if len(parts) < 2 {
 			continue // Skip empty or malformed lines
@@ -125,19 +114,95 @@ func ParseCommit(data string) Commit {
 			commit.TreeHash = value
 		case "parent":
 			commit.ParentHash = value
+		case "author":
+			commit.Author = value
 		case "timestamp":
 			timestamp, err := strconv.ParseInt(value, 10, 64)
 			if err == nil {
 				commit.TimeStamp = timestamp
 			}
 		case "message":
-			commit.Message = value
+			// The message is the last field and may span several lines.
+			commit.Message = strings.TrimSuffix(value+"\n"+data, "\n")
+			return commit
 		}
 	}
 
 	return commit
 }
 
+// WriteIndexTree stores the index as a tree and returns the tree hash.
+func WriteIndexTree() (string, error) {
+	index, err := ReadIndex()
+	if err != nil {
+		return "", err
+	}
+
+	tree := BuildTreeFromIndex(index)
+	WriteTree(&tree, constants.ObjectsDir)
+	return tree.Hash, nil
+}
+
+// CommitIndex records the index as a new commit on top of HEAD and moves
+// HEAD to it. The reflog entry reads "<action>: <first line of message>".
+func CommitIndex(message string, author string, action string) (Commit, error) {
+	treeHash, err := WriteIndexTree()
+	if err != nil {
+		return Commit{}, err
+	}
+
+	parent, err := GetLatestCommitHash()
+	if err != nil {
+		return Commit{}, err
+	}
+
+	commit := WriteCommit(treeHash, parent, author, message, constants.ObjectsDir)
+	if err := UpdateRefFrom("HEAD", commit.Hash, parent, action+": "+firstLine(message)); err != nil {
+		return Commit{}, err
+	}
+
+	return commit, nil
+}
+
+// AmendCommit replaces the HEAD commit with one that has the same parent and
+// the given tree, message and author. The branch moves in a single ref
+// update and the replaced commit stays reachable through the reflog.
+func AmendCommit(treeHash string, message string, author string, action string) (Commit, error) {
+	_, headHash, err := ReadHead()
+	if err != nil {
+		return Commit{}, err
+	}
+	if headHash == "" {
+		return Commit{}, fmt.Errorf("there is no commit to amend")
+	}
+	head, err := ReadCommit(headHash)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	commit := WriteCommit(treeHash, head.ParentHash, author, message, constants.ObjectsDir)
+	if err := UpdateRefFrom("HEAD", commit.Hash, headHash, action+": "+firstLine(message)); err != nil {
+		return Commit{}, err
+	}
+
+	return commit, nil
+}
+
+// ReadCommit reads and parses the commit object with the given hash.
+func ReadCommit(hash string) (Commit, error) {
+	objType, commitData, err := ReadObjectType(hash)
+	if err != nil {
+		return Commit{}, err
+	}
+	if objType != "commit" {
+		return Commit{}, fmt.Errorf("%s is a %s, not a commit", hash, objType)
+	}
+
+	commit := ParseCommit(string(commitData))
+	commit.Hash = hash
+	return commit, nil
+}
+
 // Recursive function to print commit history
 func printCommit(commitHash string) {
 	if commitHash == "" {
@@ -153,9 +218,18 @@ func printCommit(commitHash string) {
 	commitString := string(commitData)
 	commit := ParseCommit(commitString)
 
-	fmt.Printf("\nHash: %s\nTree: %s\nParent: %s\nTimestamp: %d\nMessage: %s\n",
-		commitHash, commit.TreeHash, commit.ParentHash, commit.TimeStamp, commit.Message)
+	fmt.Printf("\nHash: %s\nTree: %s\nParent: %s\n", commitHash, commit.TreeHash, commit.ParentHash)
+	if commit.Author != "" {
+		fmt.Printf("Author: %s\n", commit.Author)
+	}
+	fmt.Printf("Timestamp: %d\nMessage: %s\n", commit.TimeStamp, commit.Message)
 	fmt.Println("\n------------------------------------------------------")
 
+	// A shallow clone does not have the history beyond this commit.
+	if shallow, err := ReadShallow(); err == nil && shallow[commitHash] {
+		fmt.Println("(shallow boundary, older history was not fetched)")
+		return
+	}
+
 	printCommit(commit.ParentHash)
 }
@@ -0,0 +1,164 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Config is the contents of .gt/config, an INI style file of sections such
+// as [remote "origin"] holding "key = value" lines. Keys are addressed as
+// "section.subsection.name", e.g. "remote.origin.url".
+type Config struct {
+	sections []*configSection
+}
+
+type configSection struct {
+	Name    string
+	Sub     string
+	Entries [][2]string
+}
+
+// ReadConfig parses .gt/config. A missing file is an empty config.
+func ReadConfig() (*Config, error) {
+	return readConfigFile(repoPath(constants.ConfigFile))
+}
+
+// readConfigFile parses any file in the config format, such as .gtmodules.
+func readConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	return parseConfig(string(data))
+}
+
+func parseConfig(data string) (*Config, error) {
+	config := &Config{}
+	var section *configSection
+
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := strings.TrimSpace(line[1 : len(line)-1])
+			name, sub, _ := strings.Cut(header, " ")
+			section = &configSection{Name: name, Sub: strings.Trim(strings.TrimSpace(sub), "\"")}
+			config.sections = append(config.sections, section)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == nil {
+			return nil, fmt.Errorf("bad config line %d: %q", n+1, line)
+		}
+		section.Entries = append(section.Entries, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+
+	return config, nil
+}
+
+// Write saves the config back to .gt/config.
+func (c *Config) Write() error {
+	return c.writeFile(constants.ConfigFile)
+}
+
+func (c *Config) writeFile(path string) error {
+	var b strings.Builder
+	for _, section := range c.sections {
+		if section.Sub != "" {
+			fmt.Fprintf(&b, "[%s \"%s\"]\n", section.Name, section.Sub)
+		} else {
+			fmt.Fprintf(&b, "[%s]\n", section.Name)
+		}
+		for _, entry := range section.Entries {
+			fmt.Fprintf(&b, "\t%s = %s\n", entry[0], entry[1])
+		}
+	}
+
+	return writeFileAtomic(path, []byte(b.String()), 0644)
+}
+
+// Get returns the value of key, or "" when it is not set.
+func (c *Config) Get(key string) string {
+	name, sub, field := splitConfigKey(key)
+	if section := c.section(name, sub); section != nil {
+		for _, entry := range section.Entries {
+			if entry[0] == field {
+				return entry[1]
+			}
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of key, adding the section if needed.
+func (c *Config) Set(key string, value string) {
+	name, sub, field := splitConfigKey(key)
+	section := c.section(name, sub)
+	if section == nil {
+		section = &configSection{Name: name, Sub: sub}
+		c.sections = append(c.sections, section)
+	}
+
+	for i, entry := range section.Entries {
+		if entry[0] == field {
+			section.Entries[i][1] = value
+			return
+		}
+	}
+	section.Entries = append(section.Entries, [2]string{field, value})
+}
+
+// RemoveSection drops [name "sub"] and reports whether it existed.
+func (c *Config) RemoveSection(name string, sub string) bool {
+	for i, section := range c.sections {
+		if section.Name == name && section.Sub == sub {
+			c.sections = append(c.sections[:i], c.sections[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Subsections lists the subsection names of every [name "..."] section.
+func (c *Config) Subsections(name string) []string {
+	var subs []string
+	for _, section := range c.sections {
+		if section.Name == name && section.Sub != "" {
+			subs = append(subs, section.Sub)
+		}
+	}
+	return subs
+}
+
+func (c *Config) section(name string, sub string) *configSection {
+	for _, section := range c.sections {
+		if section.Name == name && section.Sub == sub {
+			return section
+		}
+	}
+	return nil
+}
+
+// splitConfigKey splits "remote.origin.url" into "remote", "origin" and
+// "url". The subsection may itself contain dots.
+func splitConfigKey(key string) (string, string, string) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first == -1 {
+		return key, "", ""
+	}
+	if first == last {
+		return key[:first], "", key[last+1:]
+	}
+	return key[:first], key[first+1 : last], key[last+1:]
+}
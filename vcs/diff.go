@@ -0,0 +1,195 @@
+package vcs
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DiffEqual  = ' '
+	DiffDelete = '-'
+	DiffInsert = '+'
+)
+
+// DiffOp is one line of an edit script turning a into b
+type DiffOp struct {
+	Kind byte // DiffEqual, DiffDelete or DiffInsert
+	Line string
+}
+
+// SplitLines splits content into lines that keep their "\n", so joining them
+// gives back the exact content even without a trailing newline.
+func SplitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// DiffLines computes a shortest edit script from a to b with Myers'
+// algorithm.
+func DiffLines(a []string, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Insertion: move down
+			} else {
+				x = v[offset+k-1] + 1 // Deletion: move right
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the path.
+	var ops []DiffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffOp{Kind: DiffEqual, Line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, DiffOp{Kind: DiffInsert, Line: b[y-1]})
+			} else {
+				ops = append(ops, DiffOp{Kind: DiffDelete, Line: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// matchLines maps every line of a that is kept in b to its index in b, or
+// -1 when the line was deleted.
+func matchLines(a []string, b []string) []int {
+	matches := make([]int, len(a))
+	i, j := 0, 0
+
+	for _, op := range DiffLines(a, b) {
+		switch op.Kind {
+		case DiffEqual:
+			matches[i] = j
+			i++
+			j++
+		case DiffDelete:
+			matches[i] = -1
+			i++
+		case DiffInsert:
+			j++
+		}
+	}
+
+	return matches
+}
+
+// UnifiedDiff formats the edit script from a to b as unified diff hunks with
+// up to context unchanged lines around each change.
+func UnifiedDiff(a []string, b []string, context int) string {
+	ops := DiffLines(a, b)
+
+	// oldAt[i] and newAt[i] count the lines of a and b before ops[i].
+	oldAt := make([]int, len(ops)+1)
+	newAt := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldAt[i+1], newAt[i+1] = oldAt[i], newAt[i]
+		if op.Kind != DiffInsert {
+			oldAt[i+1]++
+		}
+		if op.Kind != DiffDelete {
+			newAt[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == DiffEqual {
+			i++
+			continue
+		}
+
+		// Changes closer than twice the context share a hunk.
+		end := i + 1
+		for j := i; j < len(ops); j++ {
+			if ops[j].Kind != DiffEqual {
+				end = j + 1
+			} else if j+1-end > 2*context {
+				break
+			}
+		}
+		start := max(0, i-context)
+		stop := min(len(ops), end+context)
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(oldAt[start], oldAt[stop]-oldAt[start]),
+			hunkRange(newAt[start], newAt[stop]-newAt[start]))
+		for _, op := range ops[start:stop] {
+			out.WriteByte(op.Kind)
+			out.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the lines after skipped as "start,count", where an
+// empty range starts at the line before it.
+func hunkRange(skipped int, count int) string {
+	start := skipped + 1
+	if count == 0 {
+		start = skipped
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,194 @@
+package vcs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FastExport writes the history of the given refs (every branch and tag
+// when refs is empty) to w as a fast-import stream of blob, commit, reset
+// and tag commands. Messages get the trailing newline Git tools expect.
+func FastExport(w io.Writer, refs []string) error {
+	if len(refs) == 0 {
+		var err error
+		if refs, err = filterRefs(); err != nil {
+			return err
+		}
+	}
+
+	// A shallow clone's boundary commits are exported as roots.
+	shallow, err := ReadShallow()
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	e := &fastExporter{out: out, marks: make(map[string]int), shallow: shallow}
+
+	for _, ref := range refs {
+		if err := e.exportRef(ref); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "done")
+	return out.Flush()
+}
+
+type fastExporter struct {
+	out      *bufio.Writer
+	marks    map[string]int // Object hash to the mark it was given
+	lastMark int
+	shallow  map[string]bool
+}
+
+func (e *fastExporter) mark(hash string) int {
+	e.lastMark++
+	e.marks[hash] = e.lastMark
+	return e.lastMark
+}
+
+func (e *fastExporter) exportRef(rev string) error {
+	ref, hash, err := bundleRef(rev)
+	if err != nil {
+		return err
+	}
+
+	objType, data, err := ReadObjectType(hash)
+	if err != nil {
+		return err
+	}
+	if objType == "tag" {
+		tag := ParseTag(string(data))
+		commit, err := PeelToCommit(tag.Object)
+		if err != nil {
+			return err
+		}
+		if err := e.exportCommits(ref, commit); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "tag %s\nfrom :%d\n", tag.Name, e.marks[commit])
+		fmt.Fprintf(e.out, "tagger %s %d +0000\n", tag.Tagger, tag.TimeStamp)
+		e.writeData(tag.Message + "\n")
+		return nil
+	}
+
+	if err := e.exportCommits(ref, hash); err != nil {
+		return err
+	}
+	// Point the ref at its commit even when an earlier ref already
+	// exported it.
+	fmt.Fprintf(e.out, "reset %s\nfrom :%d\n\n", ref, e.marks[hash])
+	return nil
+}
+
+// exportCommits writes the commits leading to hash that have not been
+// written yet, oldest first.
+func (e *fastExporter) exportCommits(ref string, hash string) error {
+	var chain []Commit
+	for hash != "" {
+		if _, done := e.marks[hash]; done {
+			break
+		}
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, commit)
+		hash = parentOf(commit, e.shallow)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err := e.exportCommit(ref, chain[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *fastExporter) exportCommit(ref string, commit Commit) error {
+	parent := parentOf(commit, e.shallow)
+	parentFiles, err := FlattenCommit(parent)
+	if err != nil {
+		return err
+	}
+	files, err := FlattenTree(commit.TreeHash)
+	if err != nil {
+		return err
+	}
+
+	// Blobs the commit introduces go first so it can refer to their marks.
+	var changed []string
+	for _, path := range sortedPaths(files) {
+		entry := files[path]
+		if parent, ok := parentFiles[path]; ok && parent.Hash == entry.Hash && parent.Mode == entry.Mode {
+			continue
+		}
+		changed = append(changed, path)
+
+		if entry.Mode == GitlinkMode {
+			continue
+		}
+		if _, done := e.marks[entry.Hash]; done {
+			continue
+		}
+		content, err := ReadObject(entry.Hash)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "blob\nmark :%d\n", e.mark(entry.Hash))
+		e.writeData(string(content))
+	}
+
+	var deleted []string
+	for path := range parentFiles {
+		if _, ok := files[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+	sort.Strings(deleted)
+
+	author := commit.Author
+	if author == "" {
+		author = "Unknown <unknown>"
+	}
+
+	fmt.Fprintf(e.out, "commit %s\nmark :%d\n", ref, e.mark(commit.Hash))
+	fmt.Fprintf(e.out, "author %s %d +0000\n", author, commit.TimeStamp)
+	fmt.Fprintf(e.out, "committer %s %d +0000\n", author, commit.TimeStamp)
+	e.writeData(commit.Message + "\n")
+	if parent != "" {
+		fmt.Fprintf(e.out, "from :%d\n", e.marks[parent])
+	}
+	for _, path := range deleted {
+		fmt.Fprintf(e.out, "D %s\n", quoteFastPath(path))
+	}
+	for _, path := range changed {
+		entry := files[path]
+		if entry.Mode == GitlinkMode {
+			// Gitlinks name the submodule commit directly.
+			fmt.Fprintf(e.out, "M %s %s %s\n", entry.Mode, entry.Hash, quoteFastPath(path))
+			continue
+		}
+		fmt.Fprintf(e.out, "M %s :%d %s\n", entry.Mode, e.marks[entry.Hash], quoteFastPath(path))
+	}
+	fmt.Fprintln(e.out)
+
+	return nil
+}
+
+func (e *fastExporter) writeData(data string) {
+	fmt.Fprintf(e.out, "data %d\n%s\n", len(data), data)
+}
+
+// quoteFastPath quotes paths the stream could not carry bare.
+func quoteFastPath(path string) string {
+	if strings.ContainsAny(path, " \"\n\\") {
+		return strconv.Quote(path)
+	}
+	return path
+}
@@ -0,0 +1,562 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FastImportResult reports what a fast-import stream created.
+type FastImportResult struct {
+	Blobs   int
+	Commits int
+	Tags    int
+	Refs    []string // Refs that were created or moved, in stream order
+	Skipped []string // Refs left alone, with the reason
+	Lossy   []string // Commits imported lossily, with what was lost
+}
+
+// FastImport reads a fast-import stream from r. Blobs are stored with
+// WriteBlob, trees are built from each commit's file list and stored with
+// WriteTree, and commits are written like WriteCommit does but keeping the
+// committer time from the stream. Only the first parent of a merge is kept
+// and executable and symlink files become regular files; each such loss is
+// listed in Lossy.
+// Refs are updated at the end, except the checked out branch unless it has
+// no commits yet, in which case its files are checked out.
+func FastImport(r io.Reader) (FastImportResult, error) {
+	im := &fastImporter{
+		in:       bufio.NewReader(r),
+		marks:    make(map[string]string),
+		branches: make(map[string]string),
+	}
+
+	if err := im.run(); err != nil {
+		return im.result, fmt.Errorf("fast-import: %w", err)
+	}
+
+	return im.result, im.updateRefs()
+}
+
+type fastImporter struct {
+	in       *bufio.Reader
+	line     string            // The command line being processed
+	pending  bool              // line was read ahead and not consumed yet
+	marks    map[string]string // ":n" to object hash
+	branches map[string]string // Ref to the hash it ends up at
+	order    []string          // Refs in the order they were first written
+	lost     []string          // Losses in the commit being read
+	result   FastImportResult
+}
+
+// next reads the next non-comment line into im.line and reports false at
+// the end of the stream.
+func (im *fastImporter) next() (bool, error) {
+	if im.pending {
+		im.pending = false
+		return true, nil
+	}
+	for {
+		line, err := im.in.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return false, nil
+		}
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		im.line = line
+		return true, nil
+	}
+}
+
+func (im *fastImporter) unread() {
+	im.pending = true
+}
+
+func (im *fastImporter) run() error {
+	for {
+		ok, err := im.next()
+		if err != nil || !ok {
+			return err
+		}
+
+		command, arg, _ := strings.Cut(im.line, " ")
+		switch command {
+		case "":
+		case "blob":
+			err = im.blob()
+		case "commit":
+			err = im.commit(arg)
+		case "reset":
+			err = im.reset(arg)
+		case "tag":
+			err = im.tag(arg)
+		case "done":
+			return nil
+		case "progress":
+			fmt.Println(arg)
+		case "feature", "option", "checkpoint":
+			// Nothing to do: objects are written as they are read.
+		default:
+			err = fmt.Errorf("unsupported command: %q", im.line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// optional consumes the next line if it starts with prefix and returns the
+// rest of it.
+func (im *fastImporter) optional(prefix string) (string, bool, error) {
+	ok, err := im.next()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if value, found := strings.CutPrefix(im.line, prefix); found {
+		return value, true, nil
+	}
+	im.unread()
+	return "", false, nil
+}
+
+// data reads a "data <count>" or "data <<DELIM" block.
+func (im *fastImporter) data() ([]byte, error) {
+	ok, err := im.next()
+	if err != nil {
+		return nil, err
+	}
+	spec, found := strings.CutPrefix(im.line, "data ")
+	if !ok || !found {
+		return nil, fmt.Errorf("expected data, got %q", im.line)
+	}
+
+	if delim, ok := strings.CutPrefix(spec, "<<"); ok {
+		var b strings.Builder
+		for {
+			line, err := im.in.ReadString('\n')
+			if err != nil {
+				return nil, fmt.Errorf("unterminated data <<%s", delim)
+			}
+			if strings.TrimSuffix(line, "\n") == delim {
+				return []byte(b.String()), nil
+			}
+			b.WriteString(line)
+		}
+	}
+
+	size, err := strconv.Atoi(spec)
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("bad data length %q", spec)
+	}
+	content := make([]byte, size)
+	if _, err := io.ReadFull(im.in, content); err != nil {
+		return nil, fmt.Errorf("truncated data: %w", err)
+	}
+
+	// An optional newline follows the data.
+	if c, err := im.in.ReadByte(); err == nil && c != '\n' {
+		im.in.UnreadByte()
+	}
+	return content, nil
+}
+
+func (im *fastImporter) blob() error {
+	mark, _, err := im.optional("mark ")
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+	content, err := im.data()
+	if err != nil {
+		return err
+	}
+
+	hash, err := WriteBlobContent(content, constants.ObjectsDir)
+	if err != nil {
+		return err
+	}
+	if mark != "" {
+		im.marks[mark] = hash
+	}
+	im.result.Blobs++
+	return nil
+}
+
+// resolve turns a mark, ref or hash used in from/merge/M into a hash.
+func (im *fastImporter) resolve(ref string) (string, error) {
+	if strings.HasPrefix(ref, ":") {
+		hash, ok := im.marks[ref]
+		if !ok {
+			return "", fmt.Errorf("unknown mark %s", ref)
+		}
+		return hash, nil
+	}
+	if hash, ok := im.branches[ref]; ok {
+		return hash, nil
+	}
+	return ResolveObject(ref)
+}
+
+func (im *fastImporter) commit(ref string) error {
+	mark, _, err := im.optional("mark ")
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+	author, hasAuthor, err := im.optional("author ")
+	if err != nil {
+		return err
+	}
+	committer, hasCommitter, err := im.optional("committer ")
+	if err != nil {
+		return err
+	}
+	if !hasCommitter {
+		return fmt.Errorf("commit %s without a committer", ref)
+	}
+	if !hasAuthor {
+		author = committer
+	}
+	if _, _, err := im.optional("encoding "); err != nil {
+		return err
+	}
+	message, err := im.data()
+	if err != nil {
+		return err
+	}
+
+	// Without "from" a commit continues the branch, or starts it anew.
+	parent := im.branches[ref]
+	if from, ok, err := im.optional("from "); err != nil {
+		return err
+	} else if ok {
+		if parent, err = im.resolve(from); err != nil {
+			return err
+		}
+	} else if _, known := im.branches[ref]; !known {
+		if parent, err = ReadRef(ref); err != nil {
+			return err
+		}
+	}
+
+	files, err := FlattenCommit(parent)
+	if err != nil {
+		return err
+	}
+	if err := im.fileChanges(files); err != nil {
+		return err
+	}
+
+	tree := BuildTreeFromIndex(files)
+	WriteTree(&tree, constants.ObjectsDir)
+
+	commit := writeCommitObject(Commit{
+		TreeHash:   tree.Hash,
+		ParentHash: parent,
+		Author:     parseGitSignature(author).Identity,
+		TimeStamp:  parseGitSignature(committer).Time,
+		Message:    strings.TrimSuffix(string(message), "\n"),
+	}, constants.ObjectsDir)
+
+	for _, loss := range im.lost {
+		im.result.Lossy = append(im.result.Lossy, "commit "+commit.Hash+": "+loss)
+	}
+	im.lost = nil
+
+	if mark != "" {
+		im.marks[mark] = commit.Hash
+	}
+	im.setRef(ref, commit.Hash)
+	im.result.Commits++
+	return nil
+}
+
+// fileChanges applies the M, D, R, C and deleteall lines of a commit to
+// files. Extra "merge" parents are read and dropped.
+func (im *fastImporter) fileChanges(files map[string]IndexEntry) error {
+	for {
+		ok, err := im.next()
+		if err != nil {
+			return err
+		}
+		if !ok || im.line == "" {
+			return nil
+		}
+
+		command, args, _ := strings.Cut(im.line, " ")
+		switch command {
+		case "merge":
+			if _, err := im.resolve(args); err != nil {
+				return err
+			}
+			im.lost = append(im.lost, "merge parent "+args+" dropped")
+		case "deleteall":
+			for path := range files {
+				delete(files, path)
+			}
+		case "M":
+			if err := im.modify(files, args); err != nil {
+				return err
+			}
+		case "D":
+			path, _, err := parseFastPath(args, true)
+			if err != nil {
+				return err
+			}
+			deleteFastPath(files, path)
+		case "R", "C":
+			from, rest, err := parseFastPath(args, false)
+			if err != nil {
+				return err
+			}
+			to, _, err := parseFastPath(strings.TrimPrefix(rest, " "), true)
+			if err != nil {
+				return err
+			}
+			copyFastPath(files, from, to, command == "R")
+		default:
+			// The next command starts without a blank line in between.
+			im.unread()
+			return nil
+		}
+	}
+}
+
+func (im *fastImporter) modify(files map[string]IndexEntry, args string) error {
+	fields := strings.SplitN(args, " ", 3)
+	if len(fields) != 3 {
+		return fmt.Errorf("bad M line: %q", im.line)
+	}
+	mode, ref := fields[0], fields[1]
+	path, _, err := parseFastPath(fields[2], true)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case "100644", "644":
+	case "100755", "755", "120000":
+		// GoTrack stores every file as a regular file.
+		im.lost = append(im.lost, fmt.Sprintf("%s has mode %s, stored as 100644", path, mode))
+	case "040000":
+		return fmt.Errorf("M 040000 for %s is not supported", path)
+	case GitlinkMode:
+		// Submodule commits live in another repository and are named by
+		// their hash.
+		if len(ref) != 40 || !isHex(ref) {
+			return fmt.Errorf("gitlink %s needs a commit hash, got %q", path, ref)
+		}
+		if strings.ContainsAny(path, " \n") {
+			return fmt.Errorf("cannot import '%s': GoTrack trees cannot hold names with spaces or newlines", path)
+		}
+		files[path] = IndexEntry{Mode: GitlinkMode, Hash: ref, Path: path}
+		return nil
+	default:
+		return fmt.Errorf("unsupported mode %s for %s", mode, path)
+	}
+
+	var hash string
+	if ref == "inline" {
+		content, err := im.data()
+		if err != nil {
+			return err
+		}
+		if hash, err = WriteBlobContent(content, constants.ObjectsDir); err != nil {
+			return err
+		}
+		im.result.Blobs++
+	} else if hash, err = im.resolve(ref); err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(path, " \n") {
+		return fmt.Errorf("cannot import '%s': GoTrack trees cannot hold names with spaces or newlines", path)
+	}
+	files[path] = IndexEntry{Mode: "100644", Hash: hash, Path: path}
+	return nil
+}
+
+func (im *fastImporter) reset(ref string) error {
+	from, ok, err := im.optional("from ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		im.setRef(ref, "")
+		return nil
+	}
+
+	hash, err := im.resolve(from)
+	if err != nil {
+		return err
+	}
+	im.setRef(ref, hash)
+	return nil
+}
+
+func (im *fastImporter) tag(name string) error {
+	if _, _, err := im.optional("mark "); err != nil {
+		return err
+	}
+	from, ok, err := im.optional("from ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("tag %s without from", name)
+	}
+	target, err := im.resolve(from)
+	if err != nil {
+		return err
+	}
+	if _, _, err := im.optional("original-oid "); err != nil {
+		return err
+	}
+
+	tag := Tag{Object: target, Type: "commit", Name: name}
+	if tagger, ok, err := im.optional("tagger "); err != nil {
+		return err
+	} else if ok {
+		signature := parseGitSignature(tagger)
+		tag.Tagger, tag.TimeStamp = signature.Identity, signature.Time
+	}
+	message, err := im.data()
+	if err != nil {
+		return err
+	}
+	tag.Message = strings.TrimSuffix(string(message), "\n")
+
+	written, err := writeTagObject(tag, constants.ObjectsDir)
+	if err != nil {
+		return err
+	}
+	im.setRef("refs/tags/"+name, written.Hash)
+	im.result.Tags++
+	return nil
+}
+
+func (im *fastImporter) setRef(ref string, hash string) {
+	if _, known := im.branches[ref]; !known {
+		im.order = append(im.order, ref)
+	}
+	im.branches[ref] = hash
+}
+
+// updateRefs points every ref the stream wrote at its final commit.
+func (im *fastImporter) updateRefs() error {
+	symref, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range im.order {
+		hash := im.branches[ref]
+		if hash == "" {
+			continue
+		}
+		if err := CheckRefName(strings.TrimPrefix(ref, "refs/")); err != nil || !strings.HasPrefix(ref, "refs/") {
+			im.result.Skipped = append(im.result.Skipped, ref+" (invalid ref name)")
+			continue
+		}
+
+		current, err := ReadRef(ref)
+		if err != nil {
+			return err
+		}
+		if current == hash {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(ref, "refs/tags/"):
+			err = WriteRef(ref, hash)
+		case ref == symref && head != "":
+			im.result.Skipped = append(im.result.Skipped, ref+" (checked out)")
+			continue
+		default:
+			err = UpdateRef(ref, hash, "fast-import")
+		}
+		if err != nil {
+			return err
+		}
+		im.result.Refs = append(im.result.Refs, ref)
+
+		if ref == symref {
+			files, err := FlattenCommit(hash)
+			if err != nil {
+				return err
+			}
+			if err := UpdateWorkingTree(map[string]IndexEntry{}, files); err != nil {
+				return err
+			}
+			if err := WriteIndex(files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseFastPath reads a path that is either quoted or runs to the next
+// space, or to the end of the line when it is the last one, and returns the
+// rest of the line.
+func parseFastPath(s string, last bool) (string, string, error) {
+	if strings.HasPrefix(s, "\"") {
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				path, err := strconv.Unquote(s[:i+1])
+				return path, s[i+1:], err
+			}
+		}
+		return "", "", fmt.Errorf("unterminated quoted path: %s", s)
+	}
+	if last {
+		return s, "", nil
+	}
+	path, rest, _ := strings.Cut(s, " ")
+	return path, " " + rest, nil
+}
+
+// deleteFastPath removes a file, or every file below a directory.
+func deleteFastPath(files map[string]IndexEntry, path string) {
+	for existing := range files {
+		if matchesPathspec(existing, path) {
+			delete(files, existing)
+		}
+	}
+}
+
+// copyFastPath copies or moves a file, or every file below a directory.
+func copyFastPath(files map[string]IndexEntry, from string, to string, move bool) {
+	copied := make(map[string]IndexEntry)
+	for existing, entry := range files {
+		if !matchesPathspec(existing, from) {
+			continue
+		}
+		entry.Path = to + strings.TrimPrefix(existing, from)
+		copied[entry.Path] = entry
+		if move {
+			delete(files, existing)
+		}
+	}
+	for path, entry := range copied {
+		files[path] = entry
+	}
+}
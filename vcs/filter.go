@@ -0,0 +1,396 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+)
+
+// FilterOptions describes how gt filter rewrites each commit.
+type FilterOptions struct {
+	DropPaths   []string          // Patterns of paths to remove from every tree
+	RenamePaths map[string]string // Path or directory renames, old to new
+	Authors     map[string]string // Author rewrites, matched on "Name <email>" or the email alone
+	Messages    map[string]string // Text replacements in commit messages
+	PruneEmpty  bool              // Drop commits that no longer change anything
+}
+
+// FilterResult lists what a history rewrite changed.
+type FilterResult struct {
+	Mapping map[string]string // Old commit hash to new commit hash
+	Refs    []string          // Refs that were moved, sorted
+}
+
+// FilterHistory rewrites every commit reachable from a branch, a tag or HEAD
+// and moves those refs to the rewritten commits. The old to new mapping is
+// saved to .gt/filter-map. Commit timestamps are kept, so a commit whose
+// tree, parent, author and message come out unchanged keeps its hash.
+func FilterHistory(opts FilterOptions) (FilterResult, error) {
+	if sequencerInProgress() || rebaseInProgress() {
+		return FilterResult{}, fmt.Errorf("finish or abort the operation in progress first")
+	}
+
+	symref, oldHead, err := ReadHead()
+	if err != nil {
+		return FilterResult{}, err
+	}
+	if oldHead != "" {
+		if err := requireCleanTree(); err != nil {
+			return FilterResult{}, err
+		}
+	}
+
+	refs, err := filterRefs()
+	if err != nil {
+		return FilterResult{}, err
+	}
+
+	shallow, err := ReadShallow()
+	if err != nil {
+		return FilterResult{}, err
+	}
+	boundary := len(shallow)
+
+	mapping := make(map[string]string)
+	for _, ref := range refs {
+		hash, err := ReadRef(ref)
+		if err != nil {
+			return FilterResult{}, err
+		}
+		commit, err := PeelToCommit(hash)
+		if err != nil {
+			return FilterResult{}, err
+		}
+		if err := filterChain(commit, opts, mapping, shallow); err != nil {
+			return FilterResult{}, err
+		}
+	}
+	if symref == "" && oldHead != "" {
+		if err := filterChain(oldHead, opts, mapping, shallow); err != nil {
+			return FilterResult{}, err
+		}
+	}
+	if len(shallow) != boundary {
+		if err := writeShallow(shallow); err != nil {
+			return FilterResult{}, err
+		}
+	}
+
+	result := FilterResult{Mapping: mapping}
+	for _, ref := range refs {
+		moved, err := moveFilteredRef(ref, mapping)
+		if err != nil {
+			return result, err
+		}
+		if moved {
+			result.Refs = append(result.Refs, ref)
+		}
+	}
+	if symref == "" && oldHead != "" && mapping[oldHead] != oldHead {
+		if err := UpdateRef("HEAD", mapping[oldHead], "filter: rewrite history"); err != nil {
+			return result, err
+		}
+		result.Refs = append(result.Refs, "HEAD")
+	}
+
+	if err := writeFilterMap(mapping); err != nil {
+		return result, err
+	}
+
+	// Bring the index and working tree in line with the rewritten HEAD.
+	if newHead := mapping[oldHead]; newHead != oldHead {
+		oldFiles, err := FlattenCommit(oldHead)
+		if err != nil {
+			return result, err
+		}
+		newFiles, err := FlattenCommit(newHead)
+		if err != nil {
+			return result, err
+		}
+		if err := UpdateWorkingTree(oldFiles, newFiles); err != nil {
+			return result, err
+		}
+		if err := WriteIndex(newFiles); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// filterRefs lists the branches and tags to rewrite as full ref names.
+func filterRefs() ([]string, error) {
+	var refs []string
+	for _, prefix := range []string{"refs/heads", "refs/tags"} {
+		names, err := ListRefs(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			refs = append(refs, prefix+"/"+name)
+		}
+	}
+	return refs, nil
+}
+
+// filterChain rewrites hash and those of its ancestors that are not in
+// mapping yet, oldest first so every parent is rewritten before its child.
+// The walk stops at the shallow boundary.
+func filterChain(hash string, opts FilterOptions, mapping map[string]string, shallow map[string]bool) error {
+	var chain []Commit
+	for hash != "" {
+		if _, done := mapping[hash]; done {
+			break
+		}
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, commit)
+		hash = parentOf(commit, shallow)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		newHash, err := filterCommit(chain[i], opts, mapping, shallow)
+		if err != nil {
+			return err
+		}
+		mapping[chain[i].Hash] = newHash
+	}
+
+	return nil
+}
+
+// filterCommit writes the rewritten commit. A commit at the shallow
+// boundary keeps its parent, which was never fetched and so cannot be
+// rewritten, and its rewrite joins the boundary.
+func filterCommit(commit Commit, opts FilterOptions, mapping map[string]string, shallow map[string]bool) (string, error) {
+	files, err := FlattenTree(commit.TreeHash)
+	if err != nil {
+		return "", err
+	}
+
+	filtered, err := filterPaths(files, opts)
+	if err != nil {
+		return "", fmt.Errorf("commit %s: %w", commit.Hash[:7], err)
+	}
+	tree := BuildTreeFromIndex(filtered)
+	WriteTree(&tree, constants.ObjectsDir)
+
+	parent := ""
+	if shallow[commit.Hash] {
+		parent = commit.ParentHash
+	} else if commit.ParentHash != "" {
+		parent = mapping[commit.ParentHash]
+	}
+
+	if opts.PruneEmpty && parent != "" && !shallow[commit.Hash] {
+		pruned, err := becameEmpty(commit, tree.Hash, parent)
+		if err != nil {
+			return "", err
+		}
+		if pruned {
+			return parent, nil
+		}
+	}
+
+	rewritten := writeCommitObject(Commit{
+		TreeHash:   tree.Hash,
+		ParentHash: parent,
+		Author:     filterAuthor(commit.Author, opts.Authors),
+		TimeStamp:  commit.TimeStamp,
+		Message:    filterMessage(commit.Message, opts.Messages),
+	}, constants.ObjectsDir)
+	if shallow[commit.Hash] {
+		shallow[rewritten.Hash] = true
+	}
+
+	return rewritten.Hash, nil
+}
+
+// becameEmpty reports whether the rewritten tree matches the rewritten
+// parent's although the original commit did change something.
+func becameEmpty(commit Commit, treeHash string, newParent string) (bool, error) {
+	parent, err := ReadCommit(newParent)
+	if err != nil {
+		return false, err
+	}
+	if parent.TreeHash != treeHash {
+		return false, nil
+	}
+
+	if commit.ParentHash == "" {
+		return true, nil
+	}
+	original, err := ReadCommit(commit.ParentHash)
+	if err != nil {
+		return false, err
+	}
+	return original.TreeHash != commit.TreeHash, nil
+}
+
+// filterPaths drops and renames paths of a flattened tree. Renames that
+// put two files on the same path, or a file where another one needs a
+// directory, are an error naming the original paths.
+func filterPaths(files map[string]IndexEntry, opts FilterOptions) (map[string]IndexEntry, error) {
+	filtered := make(map[string]IndexEntry)
+	sources := make(map[string]string) // New path to the path it came from
+
+	for _, path := range sortedPaths(files) {
+		if matchesAnyFilter(path, opts.DropPaths) {
+			continue
+		}
+
+		entry := files[path]
+		entry.Path = renamePath(path, opts.RenamePaths)
+		if other, ok := sources[entry.Path]; ok {
+			return nil, fmt.Errorf("renaming %s and %s gives both the path %s", other, path, entry.Path)
+		}
+		filtered[entry.Path] = entry
+		sources[entry.Path] = path
+	}
+
+	for _, path := range sortedKeys(filtered) {
+		for dir := pathpkg.Dir(path); dir != "."; dir = pathpkg.Dir(dir) {
+			if other, ok := sources[dir]; ok {
+				return nil, fmt.Errorf("renaming puts %s at %s, below the file %s from %s", sources[path], path, dir, other)
+			}
+		}
+	}
+
+	return filtered, nil
+}
+
+// matchesAnyFilter reports whether path is one of the patterns, lies below
+// one of them, or matches one as a glob. Patterns without a slash are also
+// matched against the file name alone.
+func matchesAnyFilter(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = cleanPathspec(pattern)
+		if matchesPathspec(path, pattern) {
+			return true
+		}
+		if ok, _ := pathpkg.Match(pattern, path); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := pathpkg.Match(pattern, pathpkg.Base(path)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func renamePath(path string, renames map[string]string) string {
+	for _, from := range sortedKeys(renames) {
+		to := renames[from]
+		from = cleanPathspec(from)
+		if path == from {
+			return cleanPathspec(to)
+		}
+		if strings.HasPrefix(path, from+"/") {
+			return cleanPathspec(to) + path[len(from):]
+		}
+	}
+	return path
+}
+
+func filterAuthor(author string, authors map[string]string) string {
+	if replacement, ok := authors[author]; ok {
+		return replacement
+	}
+
+	if open := strings.LastIndex(author, "<"); open != -1 && strings.HasSuffix(author, ">") {
+		if replacement, ok := authors[author[open+1:len(author)-1]]; ok {
+			return replacement
+		}
+	}
+
+	return author
+}
+
+func filterMessage(message string, replacements map[string]string) string {
+	for _, old := range sortedKeys(replacements) {
+		message = strings.ReplaceAll(message, old, replacements[old])
+	}
+	return message
+}
+
+// moveFilteredRef points ref at the rewrite of what it pointed at. An
+// annotated tag is rewritten too, keeping its tagger and message.
+func moveFilteredRef(ref string, mapping map[string]string) (bool, error) {
+	hash, err := ReadRef(ref)
+	if err != nil {
+		return false, err
+	}
+
+	objType, data, err := ReadObjectType(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if objType != "tag" {
+		if mapping[hash] == hash {
+			return false, nil
+		}
+		if strings.HasPrefix(ref, "refs/heads/") {
+			return true, UpdateRef(ref, mapping[hash], "filter: rewrite history")
+		}
+		return true, WriteRef(ref, mapping[hash])
+	}
+
+	tag := ParseTag(string(data))
+	target, err := PeelToCommit(tag.Object)
+	if err != nil {
+		return false, err
+	}
+	if mapping[target] == target {
+		return false, nil
+	}
+
+	tag.Object = mapping[target]
+	tag.Type = "commit"
+	rewritten, err := writeTagObject(tag, constants.ObjectsDir)
+	if err != nil {
+		return false, err
+	}
+	return true, WriteRef(ref, rewritten.Hash)
+}
+
+// writeFilterMap saves the old to new commit mapping as "old new" lines.
+func writeFilterMap(mapping map[string]string) error {
+	olds := make([]string, 0, len(mapping))
+	for old := range mapping {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	var lines []string
+	for _, old := range olds {
+		lines = append(lines, old+" "+mapping[old])
+	}
+
+	return os.WriteFile(repoPath(constants.FilterMap), []byte(joinLines(lines)), 0644)
+}
+
+// ReadFilterMap returns the old to new commit mapping of the last gt filter.
+func ReadFilterMap() (map[string]string, error) {
+	data, err := os.ReadFile(repoPath(constants.FilterMap))
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make(map[string]string)
+	for _, line := range nonEmptyLines(string(data)) {
+		oldHash, newHash, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed filter map line: %q", line)
+		}
+		mapping[oldHash] = newHash
+	}
+	return mapping, nil
+}
@@ -3,12 +3,14 @@ package vcs
 import (
 	"fmt"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 )
 
 type File struct {
 	Name    string
 	Content []byte
+	Gitlink string // Commit checked out in a nested repository, "" for a file
 }
 
 type Directory struct {
@@ -65,10 +67,19 @@ func ScanDir(d *Directory, path string) {
 			if entry.Name() == ".gt" {
 				continue
 			}
+			// A nested repository is recorded by the commit it has checked
+			// out, not by its files.
+			if isNestedRepo(entryPath) {
+				if hash := nestedHead(entryPath); hash != "" {
+					d.Files = append(d.Files, &File{Name: entry.Name(), Gitlink: hash})
+				}
+				continue
+			}
 			subDir := d.AddSubDir(entry.Name())
 			ScanDir(subDir, entryPath)
 		} else {
-			if entry.Name() == "gt" {
+			// A linked worktree's .gt is a file.
+			if entry.Name() == "gt" || entry.Name() == ".gt" {
 				continue
 			}
 			data, err := os.ReadFile(entryPath)
@@ -91,27 +102,54 @@ func CreateFile(file *File, path string) error {
 	return nil
 }
 
+// ApplyTree writes the files of tree below path, skipping the ones the
+// sparse checkout patterns exclude.
 func ApplyTree(tree *Tree, path string) {
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		fmt.Println("Error reading sparse checkout:", err)
+	}
+	applyTree(tree, path, "", sparse)
+}
+
+// applyTree does the work of ApplyTree. prefix is the tree's path inside the
+// repository, which the sparse patterns are matched against.
+func applyTree(tree *Tree, path string, prefix string, sparse *SparseCheckout) {
 
 	for _, entry := range tree.Entries {
 		fullPath := filepath.Join(path, entry.Name)
+		repoPath := pathpkg.Join(prefix, entry.Name)
 
 		switch entry.Type {
 		case "blob":
+			if !sparse.Includes(repoPath) {
+				continue
+			}
 			fileContent, err := ReadObject(entry.Hash)
 			if err != nil {
 				fmt.Println("Error reading file:", err)
 				continue
 			}
-			file := File{entry.Name, fileContent}
+			// Sparse checkouts only create the directories they fill.
+			os.MkdirAll(path, os.ModePerm)
+			file := File{Name: entry.Name, Content: fileContent}
 			CreateFile(&file, fullPath)
 
+		case "commit":
+			// The submodule is cloned into the empty directory by
+			// "submodule update".
+			if sparse.Includes(repoPath) {
+				os.MkdirAll(fullPath, os.ModePerm)
+			}
+
 		case "tree":
-			os.Mkdir(fullPath, os.ModePerm)
+			if sparse == nil {
+				os.Mkdir(fullPath, os.ModePerm)
+			}
 			treeData, _ := ReadObject(entry.Hash)
 
 			subTree := ParseTree(string(treeData), entry.Hash)
-			ApplyTree(&subTree, fullPath)
+			applyTree(&subTree, fullPath, repoPath, sparse)
 
 		}
 
@@ -129,19 +167,50 @@ func cleanDirectory(dir string) error {
 			return nil
 		}
 
-		if info.Name() == "gt" || (info.IsDir() && info.Name() == ".gt") {
+		if info.Name() == "gt" || info.Name() == ".gt" {
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
+		// Checkouts never throw away a submodule's own history.
+		if info.IsDir() && isNestedRepo(path) {
+			return filepath.SkipDir
+		}
+		if info.IsDir() && containsNestedRepo(path) {
+			return nil
+		}
 
 		if err := os.RemoveAll(path); err != nil {
 			return fmt.Errorf("failed to remove %s: %w", path, err)
 		}
 
+		// The directory is gone, there is nothing left to walk into.
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// containsNestedRepo reports whether a repository is nested somewhere below
+// dir.
+func containsNestedRepo(dir string) bool {
+	found := false
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found || !info.IsDir() || path == dir {
+			return nil
+		}
+		if info.Name() == ".gt" {
+			return filepath.SkipDir
+		}
+		if isNestedRepo(path) {
+			found = true
+			return filepath.SkipDir
+		}
 		return nil
 	})
+	return found
 }
 
 func RootDir(path string) *Directory {
@@ -149,3 +218,114 @@ func RootDir(path string) *Directory {
 	ScanDir(root, path)
 	return root
 }
+
+// UpdateWorkingTree rewrites the tracked files listed in from so that the
+// working tree matches to. Files only in from are removed, missing or
+// different files are written and untracked files are left alone. Paths
+// outside the sparse checkout patterns are not touched. A gitlink only gets
+// an empty directory; the submodule inside is never removed or rewritten.
+func UpdateWorkingTree(from map[string]IndexEntry, to map[string]IndexEntry) error {
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return err
+	}
+
+	for path := range from {
+		if _, ok := to[path]; ok || !sparse.Includes(path) {
+			continue
+		}
+		if from[path].Mode == GitlinkMode {
+			// Only an empty directory goes, a cloned submodule stays.
+			os.Remove(path)
+			removeEmptyParents(path)
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removeEmptyParents(path)
+	}
+
+	for _, path := range sortedPaths(to) {
+		if !sparse.Includes(path) {
+			continue
+		}
+		entry := to[path]
+		if entry.Mode == GitlinkMode {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if content, err := os.ReadFile(path); err == nil && HashContent(content) == entry.Hash {
+			continue
+		}
+		if err := writeWorkingFile(path, entry.Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeWorkingFile writes the blob with the given hash to path, creating
+// parent directories as needed.
+func writeWorkingFile(path string, blobHash string) error {
+	content, err := ReadObject(blobHash)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, content, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, flushes it to
+// disk and renames it into place, so readers and a crash leave either the
+// old or the new content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	path = repoPath(path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gt-tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// removeEmptyParents deletes the directories above path that became empty.
+func removeEmptyParents(path string) {
+	for dir := filepath.Dir(path); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
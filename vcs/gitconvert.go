@@ -0,0 +1,461 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GitSyncResult reports what an export to or import from Git did.
+type GitSyncResult struct {
+	Objects int      // Objects converted in this run
+	Refs    []string // Refs that were created or moved, sorted
+	Skipped []string // Refs left alone, with the reason
+	Lossy   []string // Objects converted lossily, with what was lost
+}
+
+// gitMap pairs GoTrack object hashes with the hashes of the same objects in
+// Git. It is kept in .gt/git-map as "<gt hash> <git hash>" lines so repeated
+// exports and imports only convert what is new.
+type gitMap struct {
+	toGit   map[string]string
+	fromGit map[string]string
+}
+
+func readGitMap() (*gitMap, error) {
+	m := &gitMap{toGit: make(map[string]string), fromGit: make(map[string]string)}
+
+	data, err := os.ReadFile(repoPath(constants.GitMap))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range nonEmptyLines(string(data)) {
+		gtHash, gitHash, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed git map line: %q", line)
+		}
+		m.add(gtHash, gitHash)
+	}
+	return m, nil
+}
+
+func (m *gitMap) add(gtHash string, gitHash string) {
+	m.toGit[gtHash] = gitHash
+	m.fromGit[gitHash] = gtHash
+}
+
+func (m *gitMap) write() error {
+	lines := make([]string, 0, len(m.toGit))
+	for gtHash, gitHash := range m.toGit {
+		lines = append(lines, gtHash+" "+gitHash)
+	}
+	sort.Strings(lines)
+	return writeFileAtomic(constants.GitMap, []byte(joinLines(lines)), 0644)
+}
+
+// ExportGit writes every branch and tag with all the objects they need into
+// the Git repository at path, creating a bare repository if there is none.
+// Authors become both author and committer with a +0000 zone, and
+// messages get the trailing newline Git expects.
+func ExportGit(path string) (GitSyncResult, error) {
+	store, err := openGitStore(path, true)
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+	m, err := readGitMap()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	shallow, err := ReadShallow()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	e := &gitExporter{store: store, m: m, shallow: shallow}
+	refs, err := filterRefs()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	gitRefs, err := store.refs()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+	for _, ref := range refs {
+		hash, err := ReadRef(ref)
+		if err != nil {
+			return e.result, err
+		}
+		gitHash, err := e.export(hash)
+		if err != nil {
+			return e.result, err
+		}
+		if gitRefs[ref] == gitHash {
+			continue
+		}
+		if err := store.writeRef(ref, gitHash); err != nil {
+			return e.result, err
+		}
+		e.result.Refs = append(e.result.Refs, ref)
+	}
+
+	// Point a fresh repository's HEAD at the branch checked out here.
+	if symref, hash, _ := readHeadIn(store.dir); hash == "" {
+		if current, _, _ := ReadHead(); current != "" && current != symref {
+			if err := os.WriteFile(filepath.Join(store.dir, "HEAD"), []byte("ref: "+current+"\n"), 0644); err != nil {
+				return e.result, err
+			}
+		}
+	}
+
+	return e.result, m.write()
+}
+
+type gitExporter struct {
+	store   *gitStore
+	m       *gitMap
+	shallow map[string]bool
+	result  GitSyncResult
+}
+
+func (e *gitExporter) export(hash string) (string, error) {
+	if gitHash, ok := e.m.toGit[hash]; ok && e.store.has(gitHash) {
+		return gitHash, nil
+	}
+
+	objType, data, err := ReadObjectType(hash)
+	if err != nil {
+		return "", err
+	}
+
+	var gitType string
+	var gitData []byte
+	switch objType {
+	case "blob":
+		gitType, gitData = "blob", data
+	case "tree":
+		gitType = "tree"
+		if gitData, err = e.exportTree(ParseTree(string(data), hash)); err != nil {
+			return "", err
+		}
+	case "commit":
+		gitType = "commit"
+		commit := ParseCommit(string(data))
+		commit.Hash = hash
+		if gitData, err = e.exportCommit(commit); err != nil {
+			return "", err
+		}
+	case "tag":
+		gitType = "tag"
+		if gitData, err = e.exportTag(ParseTag(string(data))); err != nil {
+			return "", err
+		}
+	}
+
+	gitHash, err := e.store.write(gitType, gitData)
+	if err != nil {
+		return "", err
+	}
+	e.m.add(hash, gitHash)
+	e.result.Objects++
+	return gitHash, nil
+}
+
+func (e *gitExporter) exportTree(tree Tree) ([]byte, error) {
+	var entries []gitTreeEntry
+	for _, entry := range tree.Entries {
+		if entry.Mode == GitlinkMode {
+			// Gitlinks name the submodule commit directly.
+			entries = append(entries, gitTreeEntry{Mode: GitlinkMode, Name: entry.Name, Hash: entry.Hash})
+			continue
+		}
+		gitHash, err := e.export(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		mode := entry.Mode
+		if mode == "040000" {
+			mode = "40000"
+		}
+		entries = append(entries, gitTreeEntry{Mode: mode, Name: entry.Name, Hash: gitHash})
+	}
+
+	// Git orders entries by name, comparing directories as if they ended in "/".
+	sortKey := func(entry gitTreeEntry) string {
+		if entry.Mode == "40000" {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortKey(entries[i]) < sortKey(entries[j]) })
+
+	return encodeGitTree(entries)
+}
+
+func (e *gitExporter) exportCommit(commit Commit) ([]byte, error) {
+	tree, err := e.export(commit.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "tree %s\n", tree)
+	if commit.ParentHash != "" {
+		parent, err := e.export(commit.ParentHash)
+		if err != nil {
+			// Git needs the whole history; a parent that was never fetched
+			// can only come from an earlier export.
+			if e.shallow[commit.Hash] {
+				return nil, fmt.Errorf("cannot export %s: its parent is beyond the shallow boundary; fetch the full history first", commit.Hash)
+			}
+			return nil, err
+		}
+		fmt.Fprintf(&b, "parent %s\n", parent)
+	}
+
+	author := commit.Author
+	if author == "" {
+		author = "Unknown <unknown>"
+	}
+	signature := gitSignature{Identity: author, Time: commit.TimeStamp, Zone: "+0000"}
+	fmt.Fprintf(&b, "author %s\ncommitter %s\n\n%s\n", signature, signature, commit.Message)
+
+	return []byte(b.String()), nil
+}
+
+func (e *gitExporter) exportTag(tag Tag) ([]byte, error) {
+	object, err := e.export(tag.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := gitSignature{Identity: tag.Tagger, Time: tag.TimeStamp, Zone: "+0000"}
+	return []byte(fmt.Sprintf("object %s\ntype %s\ntag %s\ntagger %s\n\n%s\n",
+		object, tag.Type, tag.Name, signature, tag.Message)), nil
+}
+
+// ImportGit converts the branches and tags of the Git repository at path,
+// and everything they need, into this repository. Only the first parent of
+// a merge is kept and executable and symlink entries become regular files;
+// each such loss is listed in Lossy. The checked out branch is only updated
+// while it has no commits, in which case its files are checked out.
+func ImportGit(path string) (GitSyncResult, error) {
+	store, err := openGitStore(path, false)
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+	m, err := readGitMap()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	refs, err := store.refs()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	im := &gitImporter{store: store, m: m}
+	symref, head, err := ReadHead()
+	if err != nil {
+		return GitSyncResult{}, err
+	}
+
+	for _, ref := range sortedKeys(refs) {
+		if !strings.HasPrefix(ref, "refs/heads/") && !strings.HasPrefix(ref, "refs/tags/") {
+			continue
+		}
+
+		hash, err := im.importObject(refs[ref])
+		if err != nil {
+			return im.result, err
+		}
+
+		current, err := ReadRef(ref)
+		if err != nil {
+			return im.result, err
+		}
+		if current == hash {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(ref, "refs/tags/"):
+			err = WriteRef(ref, hash)
+		case ref == symref && head != "":
+			im.result.Skipped = append(im.result.Skipped, ref+" (checked out)")
+			continue
+		default:
+			err = UpdateRef(ref, hash, "import-git: "+path)
+		}
+		if err != nil {
+			return im.result, err
+		}
+		im.result.Refs = append(im.result.Refs, ref)
+
+		if ref == symref {
+			files, err := FlattenCommit(hash)
+			if err != nil {
+				return im.result, err
+			}
+			if err := UpdateWorkingTree(map[string]IndexEntry{}, files); err != nil {
+				return im.result, err
+			}
+			if err := WriteIndex(files); err != nil {
+				return im.result, err
+			}
+		}
+	}
+
+	return im.result, m.write()
+}
+
+type gitImporter struct {
+	store  *gitStore
+	m      *gitMap
+	result GitSyncResult
+}
+
+func (im *gitImporter) importObject(gitHash string) (string, error) {
+	if hash, ok := im.m.fromGit[gitHash]; ok && ObjectExists(hash) {
+		return hash, nil
+	}
+
+	object, err := im.store.read(gitHash)
+	if err != nil {
+		return "", err
+	}
+
+	var hash string
+	switch object.Type {
+	case "blob":
+		hash, err = WriteBlobContent(object.Data, constants.ObjectsDir)
+	case "tree":
+		hash, err = im.importTree(gitHash, object.Data)
+	case "commit":
+		hash, err = im.importCommit(gitHash, object.Data)
+	case "tag":
+		hash, err = im.importTag(object.Data)
+	default:
+		err = fmt.Errorf("unknown git object type %q", object.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	im.m.add(hash, gitHash)
+	im.result.Objects++
+	return hash, nil
+}
+
+func (im *gitImporter) importTree(gitHash string, data []byte) (string, error) {
+	gitEntries, err := parseGitTree(data)
+	if err != nil {
+		return "", err
+	}
+
+	// GoTrack lists files before directories, each sorted by name.
+	var files, dirs []TreeEntry
+	for _, gitEntry := range gitEntries {
+		if strings.ContainsAny(gitEntry.Name, " \n") {
+			return "", fmt.Errorf("cannot import '%s': GoTrack trees cannot hold names with spaces or newlines", gitEntry.Name)
+		}
+
+		switch gitEntry.Mode {
+		case "40000":
+			hash, err := im.importObject(gitEntry.Hash)
+			if err != nil {
+				return "", err
+			}
+			dirs = append(dirs, TreeEntry{Mode: "040000", Type: "tree", Hash: hash, Name: gitEntry.Name})
+		case GitlinkMode:
+			// Submodule commits live in another repository and keep their hash.
+			files = append(files, TreeEntry{Mode: GitlinkMode, Type: "commit", Hash: gitEntry.Hash, Name: gitEntry.Name})
+		default:
+			hash, err := im.importObject(gitEntry.Hash)
+			if err != nil {
+				return "", err
+			}
+			if gitEntry.Mode != "100644" {
+				im.lose("tree %s: %s has mode %s, stored as 100644", gitHash, gitEntry.Name, gitEntry.Mode)
+			}
+			files = append(files, TreeEntry{Mode: "100644", Type: "blob", Hash: hash, Name: gitEntry.Name})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name < dirs[j].Name })
+
+	tree := constructTree(append(files, dirs...))
+	return tree.Hash, writeObjectFile(tree.Hash, tree.Content, constants.ObjectsDir)
+}
+
+func (im *gitImporter) importCommit(gitHash string, data []byte) (string, error) {
+	headers, message := parseGitHeaders(data)
+	if len(headers["tree"]) != 1 {
+		return "", fmt.Errorf("git commit without a tree")
+	}
+
+	tree, err := im.importObject(headers["tree"][0])
+	if err != nil {
+		return "", err
+	}
+
+	parent := ""
+	if parents := headers["parent"]; len(parents) > 0 {
+		if parent, err = im.importObject(parents[0]); err != nil {
+			return "", err
+		}
+		for _, dropped := range parents[1:] {
+			im.lose("commit %s: merge parent %s dropped", gitHash, dropped)
+		}
+	}
+
+	commit := Commit{TreeHash: tree, ParentHash: parent, Message: strings.TrimSuffix(message, "\n")}
+	if authors := headers["author"]; len(authors) > 0 {
+		commit.Author = parseGitSignature(authors[0]).Identity
+	}
+	if committers := headers["committer"]; len(committers) > 0 {
+		commit.TimeStamp = parseGitSignature(committers[0]).Time
+	}
+
+	return writeCommitObject(commit, constants.ObjectsDir).Hash, nil
+}
+
+// lose records a loss the conversion could not avoid.
+func (im *gitImporter) lose(format string, args ...any) {
+	im.result.Lossy = append(im.result.Lossy, fmt.Sprintf(format, args...))
+}
+
+func (im *gitImporter) importTag(data []byte) (string, error) {
+	headers, message := parseGitHeaders(data)
+	if len(headers["object"]) != 1 {
+		return "", fmt.Errorf("git tag without an object")
+	}
+
+	object, err := im.importObject(headers["object"][0])
+	if err != nil {
+		return "", err
+	}
+
+	tag := Tag{Object: object, Message: strings.TrimSuffix(message, "\n")}
+	if types := headers["type"]; len(types) > 0 {
+		tag.Type = types[0]
+	}
+	if names := headers["tag"]; len(names) > 0 {
+		tag.Name = names[0]
+	}
+	if taggers := headers["tagger"]; len(taggers) > 0 {
+		signature := parseGitSignature(taggers[0])
+		tag.Tagger, tag.TimeStamp = signature.Identity, signature.Time
+	}
+
+	written, err := writeTagObject(tag, constants.ObjectsDir)
+	return written.Hash, err
+}
@@ -0,0 +1,458 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gitStore reads and writes the object database and refs of a Git
+// repository, either a bare one or the .git directory of a working tree.
+// Objects are read from loose files and from packs; new objects are always
+// written loose.
+type gitStore struct {
+	dir    string               // The Git directory holding objects/, refs/ and HEAD
+	packed map[string]gitObject // Objects unpacked from objects/pack, by hash
+}
+
+type gitObject struct {
+	Type string
+	Data []byte
+}
+
+// openGitStore finds the Git directory for path: path/.git, or path itself
+// when it is a bare repository. With create set, a missing repository is
+// initialized as a bare one.
+func openGitStore(path string, create bool) (*gitStore, error) {
+	for _, dir := range []string{filepath.Join(path, ".git"), path} {
+		if _, err := os.Stat(filepath.Join(dir, "objects")); err == nil {
+			if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+				return &gitStore{dir: dir}, nil
+			}
+		}
+	}
+
+	if !create {
+		return nil, fmt.Errorf("'%s' is not a Git repository", path)
+	}
+
+	for _, sub := range []string{"objects", "refs/heads", "refs/tags"} {
+		if err := os.MkdirAll(filepath.Join(path, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	config := "[core]\n\trepositoryformatversion = 0\n\tfilemode = true\n\tbare = true\n"
+	if err := os.WriteFile(filepath.Join(path, "config"), []byte(config), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(path, "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		return nil, err
+	}
+	return &gitStore{dir: path}, nil
+}
+
+func gitObjectHash(objType string, data []byte) string {
+	sum := sha1.Sum(append([]byte(fmt.Sprintf("%s %d\x00", objType, len(data))), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *gitStore) loosePath(hash string) string {
+	return filepath.Join(s.dir, "objects", hash[:2], hash[2:])
+}
+
+func (s *gitStore) has(hash string) bool {
+	if _, err := os.Stat(s.loosePath(hash)); err == nil {
+		return true
+	}
+	if err := s.loadPacks(); err != nil {
+		return false
+	}
+	_, ok := s.packed[hash]
+	return ok
+}
+
+// write stores an object as a zlib compressed loose file and returns its hash.
+func (s *gitStore) write(objType string, data []byte) (string, error) {
+	hash := gitObjectHash(objType, data)
+	path := s.loosePath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	fmt.Fprintf(zw, "%s %d\x00", objType, len(data))
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	// Git stores loose objects read-only.
+	return hash, writeFileAtomic(path, compressed.Bytes(), 0444)
+}
+
+func (s *gitStore) read(hash string) (gitObject, error) {
+	compressed, err := os.ReadFile(s.loosePath(hash))
+	if os.IsNotExist(err) {
+		if err := s.loadPacks(); err != nil {
+			return gitObject{}, err
+		}
+		if object, ok := s.packed[hash]; ok {
+			return object, nil
+		}
+		return gitObject{}, fmt.Errorf("git object %s not found", hash)
+	}
+	if err != nil {
+		return gitObject{}, err
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return gitObject{}, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return gitObject{}, err
+	}
+
+	nul := bytes.IndexByte(raw, 0)
+	if nul == -1 {
+		return gitObject{}, fmt.Errorf("corrupt git object %s", hash)
+	}
+	objType, _, _ := strings.Cut(string(raw[:nul]), " ")
+	return gitObject{Type: objType, Data: raw[nul+1:]}, nil
+}
+
+// loadPacks unpacks every objects/pack/*.pack into memory once.
+func (s *gitStore) loadPacks() error {
+	if s.packed != nil {
+		return nil
+	}
+	s.packed = make(map[string]gitObject)
+
+	packs, err := filepath.Glob(filepath.Join(s.dir, "objects", "pack", "*.pack"))
+	if err != nil {
+		return err
+	}
+	for _, pack := range packs {
+		data, err := os.ReadFile(pack)
+		if err != nil {
+			return err
+		}
+		if err := s.unpack(data); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(pack), err)
+		}
+	}
+	return nil
+}
+
+const (
+	gitPackCommit   = 1
+	gitPackTree     = 2
+	gitPackBlob     = 3
+	gitPackTag      = 4
+	gitPackOfsDelta = 6
+	gitPackRefDelta = 7
+)
+
+var gitPackTypes = map[int]string{
+	gitPackCommit: "commit",
+	gitPackTree:   "tree",
+	gitPackBlob:   "blob",
+	gitPackTag:    "tag",
+}
+
+// unpack reads a version 2 pack file, resolving deltas against objects
+// earlier in the same pack or already known.
+func (s *gitStore) unpack(data []byte) error {
+	if len(data) < 12 || string(data[:4]) != "PACK" {
+		return fmt.Errorf("not a pack file")
+	}
+	if version := binary.BigEndian.Uint32(data[4:8]); version != 2 && version != 3 {
+		return fmt.Errorf("unsupported pack version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+
+	byOffset := make(map[int]gitObject, count)
+	offset := 12
+
+	for i := 0; i < count; i++ {
+		start := offset
+		if offset >= len(data) {
+			return fmt.Errorf("truncated pack")
+		}
+
+		// Type and size: 3 type bits and 4 size bits, then 7 size bits
+		// per byte while the high bit is set.
+		c := data[offset]
+		offset++
+		kind := int(c>>4) & 7
+		for c&0x80 != 0 {
+			if offset >= len(data) {
+				return fmt.Errorf("truncated pack")
+			}
+			c = data[offset]
+			offset++
+		}
+
+		var base gitObject
+		switch kind {
+		case gitPackOfsDelta:
+			c := data[offset]
+			offset++
+			distance := int(c & 0x7f)
+			for c&0x80 != 0 {
+				c = data[offset]
+				offset++
+				distance = ((distance + 1) << 7) | int(c&0x7f)
+			}
+			var ok bool
+			if base, ok = byOffset[start-distance]; !ok {
+				return fmt.Errorf("delta base at offset %d not found", start-distance)
+			}
+		case gitPackRefDelta:
+			baseHash := hex.EncodeToString(data[offset : offset+20])
+			offset += 20
+			var ok bool
+			if base, ok = s.packed[baseHash]; !ok {
+				return fmt.Errorf("delta base %s not found", baseHash)
+			}
+		}
+
+		reader := bytes.NewReader(data[offset:])
+		zr, err := zlib.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			return err
+		}
+		zr.Close()
+		offset = len(data) - reader.Len()
+
+		var object gitObject
+		switch kind {
+		case gitPackOfsDelta, gitPackRefDelta:
+			resolved, err := applyGitDelta(base.Data, content)
+			if err != nil {
+				return err
+			}
+			object = gitObject{Type: base.Type, Data: resolved}
+		default:
+			objType, ok := gitPackTypes[kind]
+			if !ok {
+				return fmt.Errorf("unknown pack object type %d", kind)
+			}
+			object = gitObject{Type: objType, Data: content}
+		}
+
+		byOffset[start] = object
+		s.packed[gitObjectHash(object.Type, object.Data)] = object
+	}
+
+	return nil
+}
+
+// applyGitDelta rebuilds an object from its base and a delta of copy and
+// insert instructions.
+func applyGitDelta(base []byte, delta []byte) ([]byte, error) {
+	pos := 0
+	readSize := func() int {
+		size, shift := 0, 0
+		for pos < len(delta) {
+			c := delta[pos]
+			pos++
+			size |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		return size
+	}
+
+	if readSize() != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch")
+	}
+	result := make([]byte, 0, readSize())
+
+	for pos < len(delta) {
+		op := delta[pos]
+		pos++
+
+		if op&0x80 == 0 {
+			// Insert the next op bytes.
+			n := int(op)
+			if n == 0 || pos+n > len(delta) {
+				return nil, fmt.Errorf("corrupt delta")
+			}
+			result = append(result, delta[pos:pos+n]...)
+			pos += n
+			continue
+		}
+
+		// Copy from the base; the low bits say which offset and size bytes follow.
+		offset, size := 0, 0
+		for i := 0; i < 4; i++ {
+			if op&(1<<i) != 0 {
+				offset |= int(delta[pos]) << (8 * i)
+				pos++
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if op&(0x10<<i) != 0 {
+				size |= int(delta[pos]) << (8 * i)
+				pos++
+			}
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > len(base) {
+			return nil, fmt.Errorf("corrupt delta")
+		}
+		result = append(result, base[offset:offset+size]...)
+	}
+
+	return result, nil
+}
+
+// refs returns every branch and tag of the repository, from loose ref
+// files and packed-refs, by full ref name.
+func (s *gitStore) refs() (map[string]string, error) {
+	refs := make(map[string]string)
+
+	if data, err := os.ReadFile(filepath.Join(s.dir, "packed-refs")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+				continue
+			}
+			hash, ref, ok := strings.Cut(line, " ")
+			if ok {
+				refs[ref] = hash
+			}
+		}
+	}
+
+	for _, prefix := range []string{"refs/heads", "refs/tags"} {
+		names, err := listRefsIn(s.dir, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			hash, err := readRefIn(s.dir, prefix+"/"+name)
+			if err != nil {
+				return nil, err
+			}
+			refs[prefix+"/"+name] = hash
+		}
+	}
+
+	return refs, nil
+}
+
+func (s *gitStore) writeRef(ref string, hash string) error {
+	return writeRefIn(s.dir, ref, hash)
+}
+
+// gitTreeEntry is one entry of a binary Git tree object.
+type gitTreeEntry struct {
+	Mode string
+	Name string
+	Hash string
+}
+
+func parseGitTree(data []byte) ([]gitTreeEntry, error) {
+	var entries []gitTreeEntry
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if space == -1 || nul < space || nul+21 > len(data) {
+			return nil, fmt.Errorf("corrupt git tree")
+		}
+		entries = append(entries, gitTreeEntry{
+			Mode: string(data[:space]),
+			Name: string(data[space+1 : nul]),
+			Hash: hex.EncodeToString(data[nul+1 : nul+21]),
+		})
+		data = data[nul+21:]
+	}
+	return entries, nil
+}
+
+func encodeGitTree(entries []gitTreeEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		raw, err := hex.DecodeString(entry.Hash)
+		if err != nil || len(raw) != 20 {
+			return nil, fmt.Errorf("bad hash %q in tree entry %s", entry.Hash, entry.Name)
+		}
+		buf.WriteString(entry.Mode + " " + entry.Name + "\x00")
+		buf.Write(raw)
+	}
+	return buf.Bytes(), nil
+}
+
+// gitSignature is the "Name <email> <unix time> <zone>" of an author,
+// committer or tagger line.
+type gitSignature struct {
+	Identity string
+	Time     int64
+	Zone     string
+}
+
+func parseGitSignature(value string) gitSignature {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return gitSignature{Identity: value}
+	}
+	when, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return gitSignature{Identity: value}
+	}
+	return gitSignature{
+		Identity: strings.Join(fields[:len(fields)-2], " "),
+		Time:     when,
+		Zone:     fields[len(fields)-1],
+	}
+}
+
+func (g gitSignature) String() string {
+	return fmt.Sprintf("%s %d %s", g.Identity, g.Time, g.Zone)
+}
+
+// parseGitHeaders splits a commit or tag into its header lines and message.
+// Continuation lines (starting with a space, as in gpgsig) are dropped.
+func parseGitHeaders(data []byte) (map[string][]string, string) {
+	headers := make(map[string][]string)
+	reader := bufio.NewReader(bytes.NewReader(data))
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" || err != nil {
+			break
+		}
+		if strings.HasPrefix(line, " ") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		headers[key] = append(headers[key], value)
+	}
+
+	rest, _ := io.ReadAll(reader)
+	return headers, string(rest)
+}
@@ -0,0 +1,160 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// GrepOptions selects what Grep searches and how the pattern matches.
+type GrepOptions struct {
+	IgnoreCase bool     // Match letters of either case
+	WordRegexp bool     // Only match whole words
+	Cached     bool     // Search the blobs staged in the index
+	Worktree   bool     // Search the tracked files in the working tree
+	Paths      []string // Only search below these paths
+}
+
+// GrepLine is a matching line.
+type GrepLine struct {
+	Number int
+	Text   string // The line without its "\n"
+}
+
+// GrepFile lists the matching lines of one file.
+type GrepFile struct {
+	Path   string
+	Lines  []GrepLine
+	Binary bool // The file is binary, so no lines are listed
+}
+
+// Grep searches the files of rev, HEAD by default, for lines matching the
+// regular expression pattern. With opts.Cached or opts.Worktree it searches
+// the index or the working tree instead. Files are read and searched in
+// parallel; the ones with matches are returned sorted by path.
+func Grep(pattern string, rev string, opts GrepOptions) ([]GrepFile, error) {
+	if opts.WordRegexp {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := grepSource(rev, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, path := range sortedPaths(files) {
+		if files[path].Mode != GitlinkMode && grepPathspec(path, opts.Paths) {
+			paths = append(paths, path)
+		}
+	}
+
+	load := func(path string) ([]byte, error) {
+		return ReadObject(files[path].Hash)
+	}
+	if opts.Worktree {
+		load = func(path string) ([]byte, error) {
+			content, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				// Deleted, or left out by a sparse checkout.
+				return nil, nil
+			}
+			return content, err
+		}
+	}
+
+	results := make([]GrepFile, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				content, err := load(paths[i])
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i] = grepContent(re, paths[i], content)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var matched []GrepFile
+	for i, result := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if result.Binary || len(result.Lines) > 0 {
+			matched = append(matched, result)
+		}
+	}
+	return matched, nil
+}
+
+// grepSource lists the files Grep searches, keyed by path.
+func grepSource(rev string, opts GrepOptions) (map[string]IndexEntry, error) {
+	if opts.Cached || opts.Worktree {
+		if rev != "" {
+			return nil, fmt.Errorf("a revision cannot be searched together with the index or working tree")
+		}
+		return ReadIndex()
+	}
+
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	return FlattenCommit(hash)
+}
+
+func grepPathspec(path string, specs []string) bool {
+	if len(specs) == 0 {
+		return true
+	}
+	for _, spec := range specs {
+		if matchesPathspec(path, cleanPathspec(spec)) {
+			return true
+		}
+	}
+	return false
+}
+
+func grepContent(re *regexp.Regexp, path string, content []byte) GrepFile {
+	result := GrepFile{Path: path}
+
+	if bytes.IndexByte(content, 0) != -1 {
+		result.Binary = re.Match(content)
+		return result
+	}
+
+	for i, line := range SplitLines(content) {
+		line = strings.TrimSuffix(line, "\n")
+		if re.MatchString(line) {
+			result.Lines = append(result.Lines, GrepLine{Number: i + 1, Text: line})
+		}
+	}
+	return result
+}
@@ -3,9 +3,12 @@ package vcs
 import (
 	"GoTrack/constants"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 func HandleInit(cwd string) {
@@ -18,6 +21,7 @@ func HandleInit(cwd string) {
 	}
 
 	os.MkdirAll(objectsDir, 0755)
+	os.WriteFile(filepath.Join(gtDir, "HEAD"), []byte("ref: refs/heads/"+constants.DefaultBranch+"\n"), 0644)
 	fmt.Println(".gt directory and subdirectories created successfully.")
 }
 
@@ -31,19 +35,83 @@ func HandleCommit(commitMessage string, cwd string) {
 
 	objectsDir := filepath.Join(cwd, constants.ObjectsDir)
 
-	fileTree := RootDir(cwd)
-
-	fileTree.PrintFileTree("")
+	// The index and objects are read and written relative to the process,
+	// so that is where the repository has to be.
+	if _, err := os.Stat(repoPath(constants.ObjectsDir)); err != nil {
+		fmt.Println("Error: not a GoTrack repository:", err)
+		return
+	}
 
-	tree := BuildTree(fileTree)
-	WriteTree(&tree, objectsDir)
+	// Only what was staged is committed.
+	treeHash, err := WriteIndexTree()
+	if err != nil {
+		fmt.Println("Error reading index:", err)
+		return
+	}
 	latestCommit, _ := GetLatestCommitHash()
 
-	commit := WriteCommit(tree.Hash, latestCommit, commitMessage, objectsDir)
+	commit := WriteCommit(treeHash, latestCommit, GetIdentity(), commitMessage, objectsDir)
+
+	reason := "commit: "
+	if latestCommit == "" {
+		reason = "commit (initial): "
+	}
+	// A commit made concurrently on top of the same parent must not be lost.
+	if err := UpdateRefFrom("HEAD", commit.Hash, latestCommit, reason+firstLine(commitMessage)); err != nil {
+		fmt.Println("Error updating HEAD:", err)
+		return
+	}
+
+	// The index now matches what was just committed.
+	if err := syncIndex(commit.Hash); err != nil {
+		fmt.Println("Error updating index:", err)
+	}
+
+}
+
+// HandleCommitAmend replaces the last commit with one of the index, keeping
+// its parent and author. An empty message keeps the old one.
+func HandleCommitAmend(commitMessage string, cwd string) {
+
+	GTDirPath := filepath.Join(cwd, constants.GTDir)
+	if _, err := os.Stat(GTDirPath); os.IsNotExist(err) {
+		log.Fatal("GoTrack is not initilized.")
+		return
+	}
+
+	latestCommit, err := GetLatestCommitHash()
+	if err != nil || latestCommit == "" {
+		fmt.Println("Error: there is no commit to amend")
+		return
+	}
+	head, err := ReadCommit(latestCommit)
+	if err != nil {
+		fmt.Println("Error reading commit:", err)
+		return
+	}
 
-	UpdateLatestCommit(GTDirPath, commit.Hash)
-	UpdateCurrentCommit(GTDirPath, commit.Hash)
+	if commitMessage == "" {
+		commitMessage = head.Message
+	}
+	author := head.Author
+	if author == "" {
+		author = GetIdentity()
+	}
+
+	treeHash, err := WriteIndexTree()
+	if err != nil {
+		fmt.Println("Error reading index:", err)
+		return
+	}
+	commit, err := AmendCommit(treeHash, commitMessage, author, "commit (amend)")
+	if err != nil {
+		fmt.Println("Error amending commit:", err)
+		return
+	}
 
+	if err := syncIndex(commit.Hash); err != nil {
+		fmt.Println("Error updating index:", err)
+	}
 }
 
 func HandleLog() {
@@ -56,20 +124,49 @@ func HandleLog() {
 	printCommit(latestCommit)
 }
 
-func HandleCheckout(cwd string, hash string) {
+func HandleCheckout(cwd string, rev string) {
 
-	GTDirPath := filepath.Join(cwd, constants.GTDir)
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	from, err := headName()
+	if err != nil {
+		fmt.Println("Error reading HEAD:", err)
+		return
+	}
+
+	// Checking out a branch attaches HEAD to it, anything else detaches.
+	target := hash
+	if BranchExists(rev) {
+		target = "refs/heads/" + rev
+	}
+	if err := SetHead(target, fmt.Sprintf("checkout: moving from %s to %s", from, rev)); err != nil {
+		fmt.Println("Error updating HEAD:", err)
+		return
+	}
 
-	UpdateCurrentCommit(GTDirPath, hash)
 	cleanDirectory(".")
 	commitData, _ := ReadObject(hash)
 	commit := ParseCommit(string(commitData))
 	treeData, _ := ReadObject(commit.TreeHash)
 	tree := ParseTree(string(treeData), commit.TreeHash)
 	ApplyTree(&tree, ".")
+
+	if err := syncIndex(hash); err != nil {
+		fmt.Println("Error updating index:", err)
+	}
 }
 
-func HandleCat(hash string) {
+func HandleCat(rev string) {
+	hash, err := ResolveObject(rev)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
 	data, err := ReadObject(hash)
 	if err != nil {
 		fmt.Println("Error:", err)
@@ -79,3 +176,1012 @@ func HandleCat(hash string) {
 	fmt.Println("Object Content:")
 	fmt.Print(string(data))
 }
+
+func HandleTag(name string, rev string, annotate bool, message string, force bool) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	hash, err := CreateTag(name, rev, annotate, message, force)
+	if err != nil {
+		fmt.Println("Error creating tag:", err)
+		return
+	}
+
+	fmt.Printf("Tagged %s as %s\n", hash, name)
+}
+
+func HandleTagList() {
+	tags, err := ListRefs("refs/tags")
+	if err != nil {
+		fmt.Println("Error listing tags:", err)
+		return
+	}
+
+	for _, tag := range tags {
+		fmt.Println(tag)
+	}
+}
+
+func HandleTagDelete(name string) {
+	if err := CheckRefName(name); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	hash, err := ReadRef("refs/tags/" + name)
+	if err != nil || hash == "" {
+		fmt.Printf("Error: tag '%s' not found\n", name)
+		return
+	}
+
+	if err := DeleteRef("refs/tags/" + name); err != nil {
+		fmt.Println("Error deleting tag:", err)
+		return
+	}
+
+	fmt.Printf("Deleted tag '%s' (was %s)\n", name, hash[:7])
+}
+
+func HandleBranch(name string, rev string, force bool) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	hash, err := CreateBranch(name, rev, force)
+	if err != nil {
+		fmt.Println("Error creating branch:", err)
+		return
+	}
+
+	fmt.Printf("Created branch '%s' at %s\n", name, hash[:7])
+}
+
+func HandleBranchList() {
+	branches, err := ListRefs("refs/heads")
+	if err != nil {
+		fmt.Println("Error listing branches:", err)
+		return
+	}
+
+	current, _ := CurrentBranch()
+	for _, branch := range branches {
+		if branch == current {
+			fmt.Println("* " + branch)
+		} else {
+			fmt.Println("  " + branch)
+		}
+	}
+}
+
+func HandleBranchDelete(name string) {
+	hash, err := DeleteBranch(name)
+	if err != nil {
+		fmt.Println("Error deleting branch:", err)
+		return
+	}
+
+	fmt.Printf("Deleted branch '%s' (was %s)\n", name, hash[:7])
+}
+
+func HandleReflog(name string) {
+	ref := ReflogRef(name)
+	entries, err := ReadReflog(ref)
+	if err != nil {
+		fmt.Println("Error reading reflog:", err)
+		return
+	}
+
+	if name == "" {
+		name = "HEAD"
+	}
+
+	// Newest entries first, numbered the way <name>@{n} resolves them.
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		fmt.Printf("%s %s@{%d}: %s\n", entry.NewHash[:7], name, len(entries)-1-i, entry.Reason)
+	}
+}
+
+func HandleAdd(paths []string) {
+	if err := AddToIndex(paths); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleReset(mode string, rev string, paths []string) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	if len(paths) > 0 {
+		if mode != ResetMixed {
+			fmt.Printf("Error: cannot do a %s reset with paths\n", mode)
+			return
+		}
+		if err := ResetPaths(rev, paths); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Println("Unstaged changes after reset.")
+		return
+	}
+
+	hash, err := ResetTo(rev, mode)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if mode == ResetHard {
+		commit, _ := ReadCommit(hash)
+		fmt.Printf("HEAD is now at %s %s\n", hash[:7], firstLine(commit.Message))
+	}
+}
+
+func HandleRestore(source string, paths []string, staged bool, worktree bool) {
+	// Without --staged or --worktree only the working tree is restored.
+	if !staged && !worktree {
+		worktree = true
+	}
+
+	if err := RestorePaths(source, paths, staged, worktree); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleRevert(revs []string, noCommit bool, cont bool, abort bool) {
+	var err error
+	switch {
+	case cont:
+		err = ContinueSequence("revert")
+	case abort:
+		err = AbortSequence("revert")
+	default:
+		err = StartSequence("revert", revs, noCommit)
+	}
+
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleCherryPick(revs []string, cont bool, skip bool, abort bool) {
+	var err error
+	switch {
+	case cont:
+		err = ContinueSequence("cherry-pick")
+	case skip:
+		err = SkipSequence("cherry-pick")
+	case abort:
+		err = AbortSequence("cherry-pick")
+	default:
+		err = StartSequence("cherry-pick", revs, false)
+	}
+
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleRebase(upstream string, onto string, branch string, todoFile string) {
+	if err := StartRebase(upstream, onto, branch, todoFile); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleRebaseTodo(upstream string) {
+	todo, err := RebaseTodo(upstream)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, line := range todo {
+		fmt.Println(line)
+	}
+}
+
+func HandleRebaseControl(cont bool, skip bool, abort bool) {
+	var err error
+	switch {
+	case cont:
+		err = ContinueRebase()
+	case skip:
+		err = SkipRebase()
+	case abort:
+		err = AbortRebase()
+	}
+
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// HandleFilter rewrites the whole history. Renames, author and message
+// rewrites are given as "old=new" pairs.
+func HandleFilter(drops, renames, authors, messages []string, pruneEmpty bool) {
+	opts := FilterOptions{DropPaths: drops, PruneEmpty: pruneEmpty}
+
+	var err error
+	if opts.RenamePaths, err = parseFilterPairs(renames); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if opts.Authors, err = parseFilterPairs(authors); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if opts.Messages, err = parseFilterPairs(messages); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if len(drops) == 0 && len(renames) == 0 && len(authors) == 0 && len(messages) == 0 {
+		fmt.Println("Error: nothing to rewrite; give --drop, --rename, --author or --replace-message")
+		return
+	}
+
+	result, err := FilterHistory(opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	rewritten := 0
+	for oldHash, newHash := range result.Mapping {
+		if oldHash != newHash {
+			rewritten++
+		}
+	}
+	fmt.Printf("Rewrote %d of %d commits\n", rewritten, len(result.Mapping))
+	for _, ref := range result.Refs {
+		fmt.Printf("Ref '%s' was rewritten\n", ref)
+	}
+	fmt.Println("The old to new commit mapping is in " + constants.FilterMap)
+}
+
+func parseFilterPairs(values []string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("expected old=new, got %q", value)
+		}
+		pairs[from] = to
+	}
+	return pairs, nil
+}
+
+func HandleRemoteList(verbose bool) {
+	remotes, err := ListRemotes()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, remote := range remotes {
+		if verbose {
+			fmt.Printf("%s\t%s\n", remote.Name, remote.URL)
+		} else {
+			fmt.Println(remote.Name)
+		}
+	}
+}
+
+func HandleRemoteAdd(name string, url string) {
+	if err := AddRemote(name, url); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleRemoteRemove(name string) {
+	if err := RemoveRemote(name); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleFetch(remote string) {
+	if remote == "" {
+		remote = constants.DefaultRemote
+	}
+
+	updates, err := Fetch(remote)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if len(updates) > 0 {
+		remoteInfo, _ := GetRemote(remote)
+		fmt.Println("From " + remoteInfo.URL)
+	}
+	for _, update := range updates {
+		fmt.Println(update)
+	}
+}
+
+// HandlePush pushes branch, or the current branch when empty, to remote.
+func HandlePush(remote string, branch string, force bool, setUpstream bool) {
+	if remote == "" {
+		remote = constants.DefaultRemote
+	}
+	if branch == "" {
+		current, err := CurrentBranch()
+		if err != nil || current == "" {
+			fmt.Println("Error: you are not currently on a branch")
+			return
+		}
+		branch = current
+	}
+
+	update, err := Push(remote, branch, force)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	remoteInfo, _ := GetRemote(remote)
+	fmt.Println("To " + remoteInfo.URL)
+	if update.OldHash == update.NewHash {
+		fmt.Println("Everything up-to-date")
+	} else {
+		fmt.Println(update)
+	}
+
+	if setUpstream {
+		if err := SetUpstream(branch, remote); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("Branch '%s' set up to track '%s/%s'.\n", branch, remote, branch)
+	}
+}
+
+// HandleClone clones url into dir, or into a directory named after url.
+// A depth above zero makes a shallow clone and filter "blob:none" a partial
+// one.
+func HandleClone(url string, dir string, depth int, filter string) {
+	if dir == "" {
+		dir = filepath.Base(filepath.Clean(url))
+	}
+	if depth < 0 {
+		fmt.Println("Error: depth must be positive")
+		return
+	}
+	if filter != "" && filter != "blob:none" {
+		fmt.Printf("Error: unsupported filter '%s'; only blob:none is supported\n", filter)
+		return
+	}
+
+	fmt.Printf("Cloning into '%s'...\n", dir)
+	if err := Clone(url, dir, FetchOptions{Depth: depth, BlobNone: filter == "blob:none"}); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleServe(addr string) {
+	if err := Serve(addr); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleBundleCreate(file string, revs []string) {
+	count, err := CreateBundle(file, revs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Remove(file)
+		return
+	}
+	fmt.Printf("Wrote %d objects to %s\n", count, file)
+}
+
+func HandleBundleVerify(file string) {
+	bundle, err := VerifyBundle(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	printBundleRefs(bundle)
+	if len(bundle.Prerequisites) > 0 {
+		fmt.Printf("The bundle requires %d commit(s), all of which are present.\n", len(bundle.Prerequisites))
+	}
+	fmt.Printf("%s is okay\n", file)
+}
+
+func HandleBundleUnbundle(file string) {
+	bundle, err := Unbundle(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printBundleRefs(bundle)
+}
+
+func printBundleRefs(bundle Bundle) {
+	for _, ref := range sortedKeys(bundle.Refs) {
+		fmt.Printf("%s %s\n", bundle.Refs[ref], ref)
+	}
+}
+
+func HandleExportGit(dir string) {
+	result, err := ExportGit(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printGitSyncResult("Exported", result)
+}
+
+func HandleImportGit(dir string) {
+	result, err := ImportGit(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printGitSyncResult("Imported", result)
+}
+
+func printGitSyncResult(action string, result GitSyncResult) {
+	fmt.Printf("%s %d objects\n", action, result.Objects)
+	for _, ref := range result.Refs {
+		fmt.Println("Updated " + ref)
+	}
+	for _, ref := range result.Skipped {
+		fmt.Println("Skipped " + ref)
+	}
+	for _, loss := range result.Lossy {
+		fmt.Println("Converted lossily: " + loss)
+	}
+}
+
+func HandleFastExport(refs []string) {
+	if err := FastExport(os.Stdout, refs); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+func HandleFastImport() {
+	result, err := FastImport(os.Stdin)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Printf("Imported %d blobs, %d commits and %d tags\n", result.Blobs, result.Commits, result.Tags)
+	for _, ref := range result.Refs {
+		fmt.Println("Updated " + ref)
+	}
+	for _, ref := range result.Skipped {
+		fmt.Println("Skipped " + ref)
+	}
+	for _, loss := range result.Lossy {
+		fmt.Println("Imported lossily: " + loss)
+	}
+}
+
+func HandleStatus() {
+	status, err := GetStatus()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if symref, hash, err := ReadHead(); err == nil {
+		if symref != "" {
+			fmt.Println("On branch " + strings.TrimPrefix(symref, "refs/heads/"))
+		} else {
+			fmt.Println("HEAD detached at " + hash)
+		}
+	}
+	if status.Sparse {
+		fmt.Println("You are in a sparse checkout.")
+	}
+
+	printStatusSection("Changes to be committed:", status.Staged)
+	printStatusSection("Changes not staged for commit:", status.Unstaged)
+	if len(status.Untracked) > 0 {
+		fmt.Println("\nUntracked files:")
+		for _, path := range status.Untracked {
+			fmt.Println("\t" + path)
+		}
+	}
+
+	if len(status.Staged) == 0 && len(status.Unstaged) == 0 && len(status.Untracked) == 0 {
+		fmt.Println("nothing to commit, working tree clean")
+	}
+}
+
+func printStatusSection(title string, entries []StatusEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Println("\n" + title)
+	for _, entry := range entries {
+		label := map[byte]string{'A': "new file:", 'M': "modified:", 'D': "deleted:"}[entry.Kind]
+		fmt.Printf("\t%-10s %s\n", label, entry.Path)
+	}
+}
+
+func HandleSparseCheckoutSet(patterns []string, cone bool) {
+	result, err := SetSparseCheckout(patterns, cone)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printSparseResult(result)
+}
+
+func HandleSparseCheckoutAdd(patterns []string) {
+	result, err := AddSparseCheckout(patterns)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printSparseResult(result)
+}
+
+func HandleSparseCheckoutList() {
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if sparse == nil {
+		fmt.Println("Sparse checkout is not enabled")
+		return
+	}
+
+	for _, pattern := range sparse.Patterns {
+		fmt.Println(pattern)
+	}
+}
+
+func HandleSparseCheckoutDisable() {
+	result, err := DisableSparseCheckout()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printSparseResult(result)
+}
+
+func printSparseResult(result SparseResult) {
+	fmt.Printf("Checked out %d files, removed %d\n", len(result.Added), len(result.Removed))
+	for _, path := range result.Kept {
+		fmt.Println("Kept modified file outside the sparse checkout: " + path)
+	}
+}
+
+func HandleWorktreeAdd(path string, rev string) {
+	worktree, err := AddWorktree(path, rev)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if worktree.Head != "" {
+		fmt.Printf("Prepared worktree '%s' on branch '%s'\n", worktree.Path, strings.TrimPrefix(worktree.Head, "refs/heads/"))
+	} else {
+		fmt.Printf("Prepared worktree '%s' detached at %s\n", worktree.Path, worktree.Hash)
+	}
+}
+
+func HandleWorktreeList() {
+	worktrees, err := ListWorktrees()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, worktree := range worktrees {
+		hash := worktree.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		if worktree.Head != "" {
+			fmt.Printf("%s  %s [%s]\n", worktree.Path, hash, strings.TrimPrefix(worktree.Head, "refs/heads/"))
+		} else {
+			fmt.Printf("%s  %s (detached HEAD)\n", worktree.Path, hash)
+		}
+	}
+}
+
+func HandleWorktreeRemove(path string, force bool) {
+	if err := RemoveWorktree(path, force); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleSubmoduleAdd(url string, path string) {
+	fmt.Printf("Cloning into '%s'...\n", path)
+	if err := AddSubmodule(url, path); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleSubmoduleUpdate() {
+	updated, err := UpdateSubmodules()
+	for _, submodule := range updated {
+		fmt.Printf("Submodule path '%s': checked out '%s'\n", submodule.Path, submodule.Current)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// HandleSubmoduleStatus prints the commit of each submodule, prefixed by
+// "-" when it is not cloned and "+" when it differs from the recorded one.
+func HandleSubmoduleStatus() {
+	submodules, err := ReadSubmodules()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, submodule := range submodules {
+		switch {
+		case submodule.Current == "":
+			fmt.Printf("-%s %s\n", submodule.Recorded, submodule.Path)
+		case submodule.Current != submodule.Recorded:
+			fmt.Printf("+%s %s\n", submodule.Current, submodule.Path)
+		default:
+			fmt.Printf(" %s %s\n", submodule.Current, submodule.Path)
+		}
+	}
+}
+
+func HandleBlame(path string, rev string, lineRange string, porcelain bool) {
+	lines, err := Blame(path, rev)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if lineRange != "" {
+		start, end, err := ParseLineRange(lineRange, len(lines))
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		lines = lines[start-1 : end]
+	}
+
+	if porcelain {
+		printBlamePorcelain(lines, cleanPathspec(path))
+		return
+	}
+
+	nameWidth, numberWidth := 0, 1
+	for _, line := range lines {
+		name, _ := splitAuthor(line.Commit.Author)
+		nameWidth = max(nameWidth, len(name))
+		numberWidth = max(numberWidth, len(fmt.Sprint(line.Line)))
+	}
+
+	for _, line := range lines {
+		// Lines from a commit without a parent are marked like Git does.
+		hash := line.Commit.Hash[:8]
+		if line.Boundary {
+			hash = "^" + line.Commit.Hash[:7]
+		}
+		name, _ := splitAuthor(line.Commit.Author)
+		date := time.Unix(line.Commit.TimeStamp, 0).UTC().Format("2006-01-02 15:04:05 -0700")
+		fmt.Printf("%s (%-*s %s %*d) %s\n", hash, nameWidth, name, date, numberWidth, line.Line, line.Text)
+	}
+}
+
+// printBlamePorcelain prints blame output meant for scripts: a header of
+// "<hash> <original line> <final line>" per line, with the number of lines
+// in the group on the first one, the commit details the first time a commit
+// shows up, and the line itself after a tab.
+func printBlamePorcelain(lines []BlameLine, path string) {
+	sameGroup := func(i int) bool {
+		return i > 0 && lines[i-1].Commit.Hash == lines[i].Commit.Hash && lines[i-1].OrigLine+1 == lines[i].OrigLine
+	}
+
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		hash := line.Commit.Hash
+		if sameGroup(i) {
+			fmt.Printf("%s %d %d\n", hash, line.OrigLine, line.Line)
+			fmt.Printf("\t%s\n", line.Text)
+			continue
+		}
+
+		size := 1
+		for j := i + 1; j < len(lines) && sameGroup(j); j++ {
+			size++
+		}
+		fmt.Printf("%s %d %d %d\n", hash, line.OrigLine, line.Line, size)
+
+		if !seen[hash] {
+			seen[hash] = true
+			name, email := splitAuthor(line.Commit.Author)
+			summary, _, _ := strings.Cut(line.Commit.Message, "\n")
+			fmt.Printf("author %s\nauthor-mail <%s>\nauthor-time %d\nauthor-tz +0000\n", name, email, line.Commit.TimeStamp)
+			fmt.Printf("summary %s\n", summary)
+			if line.Boundary {
+				fmt.Println("boundary")
+			}
+		}
+		fmt.Printf("filename %s\n", path)
+		fmt.Printf("\t%s\n", line.Text)
+	}
+}
+
+func HandleBisectStart(bad string, goods []string) {
+	step, err := StartBisect(bad, goods)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printBisectStep(step)
+}
+
+func HandleBisectMark(kind string, revs []string) {
+	step, err := MarkBisect(kind, revs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printBisectStep(step)
+}
+
+func HandleBisectReset() {
+	if err := ResetBisect(); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func HandleBisectLog() {
+	lines, err := BisectLog()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+func HandleBisectRun(command []string) {
+	step, err := RunBisect(command, printBisectStep)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printBisectStep(step)
+}
+
+func printBisectStep(step BisectStep) {
+	switch {
+	case step.Status != "":
+		fmt.Println("status:", step.Status)
+
+	case step.FirstBad != "":
+		fmt.Printf("%s is the first bad commit\n", step.FirstBad)
+		commit, err := ReadCommit(step.FirstBad)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if commit.Author != "" {
+			fmt.Println("Author:", commit.Author)
+		}
+		fmt.Printf("Timestamp: %d\nMessage: %s\n", commit.TimeStamp, commit.Message)
+
+	case step.Candidates != nil:
+		fmt.Println("There are only 'skip'ped commits left to test.")
+		fmt.Println("The first bad commit could be any of:")
+		for _, hash := range step.Candidates {
+			fmt.Println(hash)
+		}
+
+	default:
+		commit, err := ReadCommit(step.Next)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		summary, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Printf("Bisecting: %d revisions left to test after this (roughly %d steps)\n", step.Remaining, step.Steps)
+		fmt.Printf("[%s] %s\n", step.Next, summary)
+	}
+}
+
+// HandleGrep prints the matching lines, prefixed by "<rev>:" when searching
+// a revision. filesOnly prints only the names of matching files and count
+// the number of matching lines in each.
+func HandleGrep(pattern string, rev string, opts GrepOptions, lineNumbers bool, filesOnly bool, count bool) {
+	files, err := Grep(pattern, rev, opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	prefix := ""
+	if rev != "" {
+		prefix = rev + ":"
+	}
+
+	for _, file := range files {
+		name := prefix + file.Path
+		switch {
+		case filesOnly:
+			fmt.Println(name)
+		case file.Binary:
+			fmt.Printf("Binary file %s matches\n", name)
+		case count:
+			fmt.Printf("%s:%d\n", name, len(file.Lines))
+		default:
+			for _, line := range file.Lines {
+				if lineNumbers {
+					fmt.Printf("%s:%d:%s\n", name, line.Number, line.Text)
+				} else {
+					fmt.Printf("%s:%s\n", name, line.Text)
+				}
+			}
+		}
+	}
+}
+
+func HandleShow(revs []string) {
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+
+	for _, rev := range revs {
+		if err := Show(os.Stdout, rev); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+}
+
+// HandleCatFile prints the type ("t"), size ("s") or content ("p") of the
+// object rev names. Trees are printed one "<mode> <type> <hash>\t<name>"
+// line per entry.
+func HandleCatFile(flag string, rev string) {
+	hash, err := ResolveObject(rev)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if flag == "t" || flag == "s" {
+		objType, size, err := ReadObjectHeader(hash)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if flag == "t" {
+			fmt.Println(objType)
+		} else {
+			fmt.Println(size)
+		}
+		return
+	}
+
+	objType, data, err := ReadObjectType(hash)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if objType != "tree" {
+		fmt.Print(string(data))
+		return
+	}
+	for _, entry := range ParseTree(string(data), hash).Entries {
+		fmt.Printf("%s %s %s\t%s\n", entry.Mode, entry.Type, entry.Hash, entry.Name)
+	}
+}
+
+// CatFileExists reports whether rev names an object that is stored.
+func CatFileExists(rev string) bool {
+	hash, err := ResolveObject(rev)
+	return err == nil && ObjectExists(hash)
+}
+
+// HandleLsTree prints the entries of a tree as "<mode> <type> <hash>\t<path>".
+func HandleLsTree(rev string, opts LsTreeOptions, nameOnly bool) {
+	entries, err := LsTree(rev, opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if nameOnly {
+			fmt.Println(entry.Name)
+		} else {
+			fmt.Printf("%s %s %s\t%s\n", entry.Mode, entry.Type, entry.Hash, entry.Name)
+		}
+	}
+}
+
+// HandleLsFiles prints one path per line; with stage, index entries are
+// printed as "<mode> <hash> 0\t<path>".
+func HandleLsFiles(opts LsFilesOptions, stage bool) {
+	entries, err := LsFiles(opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if stage && entry.Hash != "" {
+			fmt.Printf("%s %s 0\t%s\n", entry.Mode, entry.Hash, entry.Path)
+		} else {
+			fmt.Println(entry.Path)
+		}
+	}
+}
+
+// HandleHashObject prints the blob hash of standard input when stdin is set
+// and of each file, storing the blobs when write is set.
+func HandleHashObject(paths []string, stdin bool, write bool) error {
+	var contents [][]byte
+	if stdin {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, content)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, content)
+	}
+
+	for _, content := range contents {
+		hash, err := HashObject(content, write)
+		if err != nil {
+			return err
+		}
+		fmt.Println(hash)
+	}
+	return nil
+}
+
+// HandleWriteTree stores the index as a tree and prints its hash.
+func HandleWriteTree() error {
+	hash, err := WriteIndexTree()
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+// HandleCommitTree stores a commit of tree and prints its hash. Without a
+// message on the command line it is read from standard input.
+func HandleCommitTree(tree string, parent string, message string, hasMessage bool) error {
+	if !hasMessage {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		message = strings.TrimSuffix(string(data), "\n")
+	}
+
+	hash, err := CommitTree(tree, parent, message)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+// HandleUpdateRef points ref at newRev if it still holds oldRev.
+func HandleUpdateRef(ref string, newRev string, oldRev string, reason string) error {
+	if reason == "" {
+		reason = "update-ref"
+	}
+	return CompareAndSwapRef(ref, newRev, oldRev, reason)
+}
@@ -0,0 +1,82 @@
+package vcs
+
+import (
+	"fmt"
+)
+
+// Ancestors returns every commit reachable from hash, including hash. In a
+// shallow repository the walk ends at the shallow boundary.
+func Ancestors(hash string) (map[string]bool, error) {
+	shallow, err := ReadShallow()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+
+	for hash != "" && !seen[hash] {
+		seen[hash] = true
+
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		hash = parentOf(commit, shallow)
+	}
+
+	return seen, nil
+}
+
+// RevList returns the commits reachable from include but not from exclude,
+// newest first. An empty exclude lists the whole history.
+func RevList(include string, exclude string) ([]string, error) {
+	shallow, err := ReadShallow()
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool)
+	if exclude != "" {
+		if excluded, err = Ancestors(exclude); err != nil {
+			return nil, err
+		}
+	}
+
+	var commits []string
+	for hash := include; hash != "" && !excluded[hash]; {
+		commits = append(commits, hash)
+
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		hash = parentOf(commit, shallow)
+	}
+
+	return commits, nil
+}
+
+// MergeBase returns the nearest commit that both a and b descend from.
+// History beyond the shallow boundary is not searched.
+func MergeBase(a string, b string) (string, error) {
+	ancestors, err := Ancestors(a)
+	if err != nil {
+		return "", err
+	}
+	shallow, err := ReadShallow()
+	if err != nil {
+		return "", err
+	}
+
+	for hash := b; hash != ""; {
+		if ancestors[hash] {
+			return hash, nil
+		}
+
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return "", err
+		}
+		hash = parentOf(commit, shallow)
+	}
+
+	return "", fmt.Errorf("%s and %s have no common ancestor", a, b)
+}
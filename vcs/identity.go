@@ -0,0 +1,34 @@
+package vcs
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+)
+
+// GetIdentity returns the "Name <email>" string recorded on new objects.
+// GT_AUTHOR_NAME and GT_AUTHOR_EMAIL take precedence over the OS user.
+func GetIdentity() string {
+	name := os.Getenv("GT_AUTHOR_NAME")
+	email := os.Getenv("GT_AUTHOR_EMAIL")
+
+	if name == "" || email == "" {
+		username := "unknown"
+		if u, err := user.Current(); err == nil && u.Username != "" {
+			username = u.Username
+		}
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "localhost"
+		}
+
+		if name == "" {
+			name = username
+		}
+		if email == "" {
+			email = username + "@" + host
+		}
+	}
+
+	return fmt.Sprintf("%s <%s>", name, email)
+}
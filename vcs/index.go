@@ -0,0 +1,185 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IndexEntry is a staged file: one "<mode> <hash> <path>" line of .gt/index
+type IndexEntry struct {
+	Mode string
+	Hash string
+	Path string // Relative to the repository root, "/" separated
+}
+
+func ReadIndex() (map[string]IndexEntry, error) {
+	entries := make(map[string]IndexEntry)
+
+	data, err := os.ReadFile(repoPath(filepath.Join(constants.GTDir, "index")))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("malformed index line: %q", line)
+		}
+		entries[parts[2]] = IndexEntry{Mode: parts[0], Hash: parts[1], Path: parts[2]}
+	}
+
+	return entries, nil
+}
+
+func WriteIndex(entries map[string]IndexEntry) error {
+	var data []byte
+	for _, path := range sortedPaths(entries) {
+		entry := entries[path]
+		data = append(data, []byte(fmt.Sprintf("%s %s %s\n", entry.Mode, entry.Hash, entry.Path))...)
+	}
+
+	// The lock keeps two processes from writing the index at once, and the
+	// rename in commit means readers never see half of it.
+	lock, err := acquireLock(repoPath(filepath.Join(constants.GTDir, "index")))
+	if err != nil {
+		return err
+	}
+	return lock.commit(data)
+}
+
+// syncIndex replaces the index with the files recorded by commitHash.
+func syncIndex(commitHash string) error {
+	files, err := FlattenCommit(commitHash)
+	if err != nil {
+		return err
+	}
+	return WriteIndex(files)
+}
+
+// AddToIndex stages the current content of each path. Directories are added
+// recursively and paths missing from the working tree are unstaged.
+func AddToIndex(paths []string) error {
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+
+	files, err := ScanWorkingTree(".")
+	if err != nil {
+		return err
+	}
+
+	// Files left out by a sparse checkout are missing on purpose and stay
+	// staged.
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		spec := cleanPathspec(path)
+
+		matched := false
+		for filePath := range files {
+			if !matchesPathspec(filePath, spec) {
+				continue
+			}
+			if files[filePath].Mode == GitlinkMode {
+				index[filePath] = files[filePath]
+				matched = true
+				continue
+			}
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			hash, err := WriteBlobContent(content, constants.ObjectsDir)
+			if err != nil {
+				return err
+			}
+			index[filePath] = IndexEntry{Mode: "100644", Hash: hash, Path: filePath}
+			matched = true
+		}
+
+		for indexPath := range index {
+			if _, ok := files[indexPath]; !ok && sparse.Includes(indexPath) && matchesPathspec(indexPath, spec) {
+				delete(index, indexPath)
+				matched = true
+			}
+		}
+
+		if !matched {
+			return fmt.Errorf("pathspec '%s' did not match any files", path)
+		}
+	}
+
+	return WriteIndex(index)
+}
+
+// ScanWorkingTree hashes every file below root without storing anything,
+// skipping the same entries as ScanDir. Nested repositories are listed as
+// gitlinks to the commit they have checked out.
+func ScanWorkingTree(root string) (map[string]IndexEntry, error) {
+	files := make(map[string]IndexEntry)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".gt" {
+				return filepath.SkipDir
+			}
+			if path == root || !isNestedRepo(path) {
+				return nil
+			}
+		} else if info.Name() == "gt" || info.Name() == ".gt" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if hash := nestedHead(path); hash != "" {
+				files[rel] = IndexEntry{Mode: GitlinkMode, Hash: hash, Path: rel}
+			}
+			return filepath.SkipDir
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = IndexEntry{Mode: "100644", Hash: HashContent(content), Path: rel}
+		return nil
+	})
+
+	return files, err
+}
+
+// cleanPathspec normalizes a user supplied path to the index form.
+func cleanPathspec(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// matchesPathspec reports whether path is spec itself or lies below it.
+func matchesPathspec(path string, spec string) bool {
+	return spec == "." || path == spec || strings.HasPrefix(path, spec+"/")
+}
+
+func sortedPaths(entries map[string]IndexEntry) []string {
+	return sortedKeys(entries)
+}
@@ -0,0 +1,70 @@
+package vcs
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// LockTimeout is how long a ref update waits for another process to release
+// the lock before giving up.
+var LockTimeout = 5 * time.Second
+
+// lockFile holds "<path>.lock", created exclusively so only one process at a
+// time can update path. The new content is written to the lock file and
+// renamed over path on commit.
+type lockFile struct {
+	path string
+	file *os.File
+}
+
+// acquireLock creates the lock for path, already mapped by repoPath,
+// retrying until LockTimeout passes.
+func acquireLock(path string) (*lockFile, error) {
+	deadline := time.Now().Add(LockTimeout)
+	wait := time.Millisecond
+	for {
+		file, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return &lockFile{path: path, file: file}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("unable to lock %s: %s.lock exists; another gt process may be running, or remove it if one crashed", path, path)
+		}
+
+		time.Sleep(wait)
+		wait = min(2*wait, 100*time.Millisecond)
+	}
+}
+
+// commit writes data to the lock file, flushes it to disk and renames it
+// over the locked path, releasing the lock.
+func (l *lockFile) commit(data []byte) error {
+	if _, err := l.file.Write(data); err != nil {
+		l.rollback()
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		l.rollback()
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		os.Remove(l.path + ".lock")
+		return err
+	}
+
+	if err := os.Rename(l.path+".lock", l.path); err != nil {
+		os.Remove(l.path + ".lock")
+		return err
+	}
+	return nil
+}
+
+// rollback releases the lock, leaving path as it was.
+func (l *lockFile) rollback() {
+	l.file.Close()
+	os.Remove(l.path + ".lock")
+}
@@ -0,0 +1,186 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LsTreeOptions selects which entries LsTree lists.
+type LsTreeOptions struct {
+	Recursive bool     // Descend into subtrees and list their entries
+	TreesOnly bool     // Only list trees
+	Paths     []string // Only list these paths and what lies below them
+}
+
+// LsTree lists the entries of the tree rev names, peeling commits and tags.
+// The Name of each entry is its path relative to the top of the tree.
+// Without opts.Recursive only the top level is listed, plus the subtrees on
+// the way to a path in opts.Paths.
+func LsTree(rev string, opts LsTreeOptions) ([]TreeEntry, error) {
+	hash, err := ResolveObject(rev)
+	if err != nil {
+		return nil, err
+	}
+	treeHash, err := peelToTree(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []string
+	for _, path := range opts.Paths {
+		specs = append(specs, strings.Trim(cleanPathspec(path), "/"))
+	}
+
+	var entries []TreeEntry
+	err = lsTree(treeHash, "", opts, specs, &entries)
+	return entries, err
+}
+
+func lsTree(treeHash string, prefix string, opts LsTreeOptions, specs []string, entries *[]TreeEntry) error {
+	treeData, err := ReadObject(treeHash)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range ParseTree(string(treeData), treeHash).Entries {
+		path := entry.Name
+		if prefix != "" {
+			path = prefix + "/" + entry.Name
+		}
+
+		matched, leadsToSpec := len(specs) == 0, false
+		for _, spec := range specs {
+			if matchesPathspec(path, spec) {
+				matched = true
+			} else if strings.HasPrefix(spec, path+"/") {
+				leadsToSpec = true
+			}
+		}
+		if !matched && !leadsToSpec {
+			continue
+		}
+
+		descend := entry.Type == "tree" && (leadsToSpec || opts.Recursive && matched)
+		if matched && (opts.TreesOnly && entry.Type == "tree" || !opts.TreesOnly && !descend) {
+			entry.Name = path
+			*entries = append(*entries, entry)
+		}
+		if descend {
+			if err := lsTree(entry.Hash, path, opts, specs, entries); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// peelToTree follows tags and commits until it reaches a tree.
+func peelToTree(hash string) (string, error) {
+	for {
+		objType, data, err := ReadObjectType(hash)
+		if err != nil {
+			return "", err
+		}
+
+		switch objType {
+		case "tree":
+			return hash, nil
+		case "commit":
+			hash = ParseCommit(string(data)).TreeHash
+		case "tag":
+			hash = ParseTag(string(data)).Object
+		default:
+			return "", fmt.Errorf("%s is a %s, not a tree", hash, objType)
+		}
+	}
+}
+
+// LsFilesOptions selects what LsFiles lists. With none of Modified, Others
+// or Ignored set it lists the index.
+type LsFilesOptions struct {
+	Modified bool     // Tracked files that differ from the index or are deleted
+	Others   bool     // Untracked files that are not ignored
+	Ignored  bool     // Untracked files matched by .gtignore, in place of Others
+	Paths    []string // Only list files below these paths
+}
+
+// LsFiles lists the files of the index and the working tree selected by
+// opts, sorted by path. Untracked files have no mode or hash.
+func LsFiles(opts LsFilesOptions) ([]IndexEntry, error) {
+	index, err := ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Modified && !opts.Others && !opts.Ignored {
+		var entries []IndexEntry
+		for _, path := range sortedPaths(index) {
+			if grepPathspec(path, opts.Paths) {
+				entries = append(entries, index[path])
+			}
+		}
+		return entries, nil
+	}
+
+	files, err := ScanWorkingTree(".")
+	if err != nil {
+		return nil, err
+	}
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := ReadIgnore()
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]IndexEntry)
+	if opts.Modified {
+		for path, entry := range index {
+			if other, ok := files[path]; sparse.Includes(path) && (!ok || other.Hash != entry.Hash) {
+				listed[path] = entry
+			}
+		}
+	}
+	for path := range files {
+		if _, tracked := index[path]; tracked {
+			continue
+		}
+		// Like Git, --others --ignored lists only the ignored files.
+		if ignored := ignore.Includes(path); opts.Ignored && ignored || !opts.Ignored && opts.Others && !ignored {
+			listed[path] = IndexEntry{Path: path}
+		}
+	}
+
+	var entries []IndexEntry
+	for _, path := range sortedPaths(listed) {
+		if grepPathspec(path, opts.Paths) {
+			entries = append(entries, listed[path])
+		}
+	}
+	return entries, nil
+}
+
+// ReadIgnore loads the patterns of .gtignore at the top of the working tree.
+// They follow the full sparse checkout rules, so Includes reports whether a
+// path is ignored. Without the file nothing is ignored.
+func ReadIgnore() (*SparseCheckout, error) {
+	data, err := os.ReadFile(constants.IgnoreFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	ignore := &SparseCheckout{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignore.Patterns = append(ignore.Patterns, line)
+	}
+	return ignore, nil
+}
@@ -0,0 +1,282 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergeResult is the outcome of a three-way merge of flattened trees
+type MergeResult struct {
+	Files      map[string]IndexEntry // Entries for the index, ours for conflicts
+	Conflicts  []string              // Conflicted paths, sorted
+	Conflicted map[string][]byte     // Working tree content for conflicted paths
+}
+
+// MergeFiles merges the changes base->ours and base->theirs line by line.
+// Overlapping changes are kept between conflict markers and reported.
+func MergeFiles(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, bool) {
+	if bytes.IndexByte(ours, 0) != -1 || bytes.IndexByte(theirs, 0) != -1 {
+		// Binary content cannot be merged line by line.
+		return ours, true
+	}
+
+	baseLines, oursLines, theirsLines := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	matchOurs := matchLines(baseLines, oursLines)
+	matchTheirs := matchLines(baseLines, theirsLines)
+
+	var merged []string
+	conflict := false
+	b, o, t := 0, 0, 0
+
+	emitChunk := func(baseChunk, oursChunk, theirsChunk []string) {
+		switch {
+		case equalLines(oursChunk, theirsChunk), equalLines(theirsChunk, baseChunk):
+			merged = append(merged, oursChunk...)
+		case equalLines(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		default:
+			conflict = true
+			merged = append(merged, "<<<<<<< "+oursLabel+"\n")
+			merged = append(merged, terminateLines(oursChunk)...)
+			merged = append(merged, "=======\n")
+			merged = append(merged, terminateLines(theirsChunk)...)
+			merged = append(merged, ">>>>>>> "+theirsLabel+"\n")
+		}
+	}
+
+	// Lines of base kept by both sides split the files into chunks that are
+	// resolved independently.
+	for i := range baseLines {
+		if matchOurs[i] < o || matchTheirs[i] < t {
+			continue
+		}
+		emitChunk(baseLines[b:i], oursLines[o:matchOurs[i]], theirsLines[t:matchTheirs[i]])
+		merged = append(merged, baseLines[i])
+		b, o, t = i+1, matchOurs[i]+1, matchTheirs[i]+1
+	}
+	emitChunk(baseLines[b:], oursLines[o:], theirsLines[t:])
+
+	return []byte(strings.Join(merged, "")), conflict
+}
+
+func equalLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// terminateLines makes sure the last line ends in "\n" so a conflict marker
+// never lands on the same line.
+func terminateLines(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	terminated := append([]string(nil), lines...)
+	terminated[len(terminated)-1] += "\n"
+	return terminated
+}
+
+// MergeTrees applies the changes between base and theirs on top of ours.
+// Cleanly merged file contents are written to the object store.
+func MergeTrees(base, ours, theirs map[string]IndexEntry, oursLabel, theirsLabel string) (MergeResult, error) {
+	result := MergeResult{
+		Files:      make(map[string]IndexEntry),
+		Conflicted: make(map[string][]byte),
+	}
+
+	paths := make(map[string]IndexEntry)
+	for _, side := range []map[string]IndexEntry{base, ours, theirs} {
+		for path, entry := range side {
+			paths[path] = entry
+		}
+	}
+
+	for _, path := range sortedPaths(paths) {
+		baseEntry, inBase := base[path]
+		oursEntry, inOurs := ours[path]
+		theirsEntry, inTheirs := theirs[path]
+
+		switch {
+		case inOurs == inTheirs && oursEntry.Hash == theirsEntry.Hash:
+			if inOurs {
+				result.Files[path] = oursEntry
+			}
+			continue
+		case inBase == inOurs && baseEntry.Hash == oursEntry.Hash:
+			if inTheirs {
+				result.Files[path] = theirsEntry
+			}
+			continue
+		case inBase == inTheirs && baseEntry.Hash == theirsEntry.Hash:
+			if inOurs {
+				result.Files[path] = oursEntry
+			}
+			continue
+		}
+
+		// Both sides changed the file and disagree.
+		if oursEntry.Mode == GitlinkMode || theirsEntry.Mode == GitlinkMode {
+			// Submodule commits cannot be merged here; ours stays recorded.
+			result.Conflicts = append(result.Conflicts, path)
+			if inOurs {
+				result.Files[path] = oursEntry
+			}
+			continue
+		}
+		if !inOurs || !inTheirs {
+			// Modified on one side, deleted on the other: keep the modified
+			// version in the working tree for the user to decide.
+			result.Conflicts = append(result.Conflicts, path)
+			if inOurs {
+				result.Files[path] = oursEntry
+			} else {
+				content, err := ReadObject(theirsEntry.Hash)
+				if err != nil {
+					return MergeResult{}, err
+				}
+				result.Conflicted[path] = content
+			}
+			continue
+		}
+
+		var baseContent []byte
+		if inBase {
+			content, err := ReadObject(baseEntry.Hash)
+			if err != nil {
+				return MergeResult{}, err
+			}
+			baseContent = content
+		}
+		oursContent, err := ReadObject(oursEntry.Hash)
+		if err != nil {
+			return MergeResult{}, err
+		}
+		theirsContent, err := ReadObject(theirsEntry.Hash)
+		if err != nil {
+			return MergeResult{}, err
+		}
+
+		merged, conflict := MergeFiles(baseContent, oursContent, theirsContent, oursLabel, theirsLabel)
+		if conflict {
+			result.Conflicts = append(result.Conflicts, path)
+			result.Files[path] = oursEntry
+			result.Conflicted[path] = merged
+			continue
+		}
+
+		hash, err := WriteBlobContent(merged, constants.ObjectsDir)
+		if err != nil {
+			return MergeResult{}, err
+		}
+		result.Files[path] = IndexEntry{Mode: oursEntry.Mode, Hash: hash, Path: path}
+	}
+
+	return result, nil
+}
+
+// applyMergeResult updates the working tree and index from ours to the
+// merge result and writes conflicted files with their markers.
+func applyMergeResult(ours map[string]IndexEntry, result MergeResult) error {
+	// Refuse before touching anything if an untracked file is in the way.
+	for _, files := range []map[string]IndexEntry{result.Files, conflictEntries(result)} {
+		if err := checkUntrackedOverwrite(ours, files); err != nil {
+			return err
+		}
+	}
+
+	if err := UpdateWorkingTree(ours, result.Files); err != nil {
+		return err
+	}
+
+	for path, content := range result.Conflicted {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return WriteIndex(result.Files)
+}
+
+// checkUntrackedOverwrite fails if writing incoming would replace a file
+// that exists in the working tree but is not tracked.
+func checkUntrackedOverwrite(tracked map[string]IndexEntry, incoming map[string]IndexEntry) error {
+	for path, entry := range incoming {
+		if _, ok := tracked[path]; ok {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err == nil && HashContent(content) != entry.Hash {
+			return fmt.Errorf("untracked working tree file '%s' would be overwritten", path)
+		}
+	}
+	return nil
+}
+
+func conflictEntries(result MergeResult) map[string]IndexEntry {
+	entries := make(map[string]IndexEntry)
+	for path, content := range result.Conflicted {
+		entries[path] = IndexEntry{Mode: "100644", Hash: HashContent(content), Path: path}
+	}
+	return entries
+}
+
+// requireCleanTree fails when the index or the tracked files in the working
+// tree differ from HEAD.
+func requireCleanTree() error {
+	_, headHash, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	headFiles, err := FlattenCommit(headHash)
+	if err != nil {
+		return err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+
+	if len(index) != len(headFiles) {
+		return fmt.Errorf("your index contains uncommitted changes; commit or reset them first")
+	}
+	for path, entry := range headFiles {
+		if index[path].Hash != entry.Hash {
+			return fmt.Errorf("your index contains uncommitted changes; commit or reset them first")
+		}
+		// Checkouts leave submodules alone, so they cannot get in the way.
+		if entry.Mode == GitlinkMode {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || HashContent(content) != entry.Hash {
+			return fmt.Errorf("your local changes to '%s' would be overwritten; commit or reset them first", path)
+		}
+	}
+
+	return nil
+}
+
+// hasConflictMarkers reports whether content still contains an unresolved
+// conflict written by MergeFiles.
+func hasConflictMarkers(content []byte) bool {
+	for _, line := range SplitLines(content) {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,10 @@
 package vcs
 
 import (
+	"GoTrack/constants"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -20,7 +20,7 @@ type Tree struct {
 
 type TreeEntry struct {
 	Mode    string
-	Type    string // "blob" or "tree"
+	Type    string // "blob", "tree" or "commit" for a gitlink
 	Hash    string
 	Name    string
 	Content []byte
@@ -28,56 +28,44 @@ type TreeEntry struct {
 }
 
 func WriteBlob(file *TreeEntry, objectsDir string) (string, error) {
-
-	blobPath := filepath.Join(objectsDir, file.Hash[:2], file.Hash[2:])
-
-	if _, err := os.Stat(blobPath); err == nil {
-		return file.Hash, nil
-	}
-
-	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+	if err := writeObjectFile(file.Hash, file.Content, objectsDir); err != nil {
 		return "", err
 	}
-
-	outFile, err := os.Create(blobPath)
-	if err != nil {
-		return "", err
-	}
-	defer outFile.Close()
-
-	if _, err := outFile.Write(file.Content); err != nil {
-		return "", err
-	}
-
 	return file.Hash, nil
 }
 
 func WriteTree(tree *TreeEntry, objectsDir string) {
 
-	treePath := filepath.Join(objectsDir, tree.Hash[:2], tree.Hash[2:])
-
-	if err := os.MkdirAll(filepath.Dir(treePath), 0755); err != nil {
-		log.Fatal(err)
-	}
-
-	if err := os.WriteFile(treePath, tree.Content, 0644); err != nil {
-		log.Fatal(err)
-	}
-
+	// Entries go first so a stored tree never points at missing objects.
 	for _, entry := range tree.Entries {
-		if entry.Type == "tree" {
+		switch entry.Type {
+		case "tree":
 			WriteTree(&entry, objectsDir)
-		} else {
+		case "blob":
 			WriteBlob(&entry, objectsDir)
 		}
 	}
 
+	if err := writeObjectFile(tree.Hash, tree.Content, objectsDir); err != nil {
+		log.Fatal(err)
+	}
+
 }
 
 func BuildTree(fileTree *Directory) TreeEntry {
 	var entries []TreeEntry
 
 	for _, file := range fileTree.Files {
+		if file.Gitlink != "" {
+			entries = append(entries, TreeEntry{
+				Mode: GitlinkMode,
+				Type: "commit",
+				Hash: file.Gitlink,
+				Name: file.Name,
+			})
+			continue
+		}
+
 		fileHash := HashContent(file.Content)
 
 		fileContentWithHeader := append([]byte(fmt.Sprintf("blob %d\000", len(file.Content))), file.Content...)
@@ -93,16 +81,87 @@ func BuildTree(fileTree *Directory) TreeEntry {
 	for _, dir := range fileTree.SubDirs {
 		subTree := BuildTree(dir)
 		entries = append(entries, TreeEntry{
-			Mode: "040000", // Directory mode
-			Type: "tree",
-			Hash: subTree.Hash,
-			Name: dir.Name,
+			Mode:    "040000", // Directory mode
+			Type:    "tree",
+			Hash:    subTree.Hash,
+			Name:    dir.Name,
+			Content: subTree.Content,
+			Entries: subTree.Entries, // Needed by WriteTree to store the subtree
 		})
 	}
 
 	return constructTree(entries)
 }
 
+// BuildTreeFromIndex nests the flat index entries into trees, ordered the
+// same way BuildTree orders a scanned directory so equal content hashes
+// equally.
+func BuildTreeFromIndex(entries map[string]IndexEntry) TreeEntry {
+	root := &indexDir{files: make(map[string]IndexEntry), dirs: make(map[string]*indexDir)}
+
+	for path, entry := range entries {
+		dir := root
+		names := strings.Split(path, "/")
+		for _, name := range names[:len(names)-1] {
+			subDir, ok := dir.dirs[name]
+			if !ok {
+				subDir = &indexDir{files: make(map[string]IndexEntry), dirs: make(map[string]*indexDir)}
+				dir.dirs[name] = subDir
+			}
+			dir = subDir
+		}
+		dir.files[names[len(names)-1]] = entry
+	}
+
+	return buildIndexDir(root)
+}
+
+type indexDir struct {
+	files map[string]IndexEntry
+	dirs  map[string]*indexDir
+}
+
+func buildIndexDir(dir *indexDir) TreeEntry {
+	var entries []TreeEntry
+
+	for _, name := range sortedKeys(dir.files) {
+		entry := dir.files[name]
+		entryType := "blob"
+		if entry.Mode == GitlinkMode {
+			entryType = "commit"
+		}
+		entries = append(entries, TreeEntry{
+			Mode: entry.Mode,
+			Type: entryType,
+			Hash: entry.Hash,
+			Name: name,
+		})
+	}
+
+	for _, name := range sortedKeys(dir.dirs) {
+		subTree := buildIndexDir(dir.dirs[name])
+		entries = append(entries, TreeEntry{
+			Mode:    "040000",
+			Type:    "tree",
+			Hash:    subTree.Hash,
+			Name:    name,
+			Content: subTree.Content,
+			Entries: subTree.Entries,
+		})
+	}
+
+	return constructTree(entries)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func constructTree(entries []TreeEntry) TreeEntry {
 	var treeData []byte
 
@@ -149,6 +208,9 @@ func ParseTree(data string, hash string) Tree {
 		case "040000":
 			treeEntry.Mode = "040000"
 			treeEntry.Type = "tree"
+		case GitlinkMode:
+			treeEntry.Mode = GitlinkMode
+			treeEntry.Type = "commit"
 		}
 		treeEntry.Name = parts[1]
 		treeEntry.Hash = parts[2]
@@ -172,3 +234,130 @@ func PrintTree(tree Tree) {
 	}
 
 }
+
+// checkTreeEntryName rejects entry names that would not stay inside the
+// directory of their tree once checked out, or that would reach into .gt.
+func checkTreeEntryName(name string) error {
+	if name == "" || name == "." || name == ".." || name == constants.GTDir || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid tree entry name %q", name)
+	}
+	return nil
+}
+
+// checkTreeNames checks every entry name of a tree object's content.
+func checkTreeNames(data []byte) error {
+	for _, entry := range ParseTree(string(data), "").Entries {
+		if err := checkTreeEntryName(entry.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkTree calls fn for every entry below the tree, depth first, with paths
+// relative to the tree root joined by "/". A tree with an entry name that
+// could not be checked out safely stops the walk with an error.
+func WalkTree(treeHash string, prefix string, fn func(path string, entry TreeEntry) error) error {
+	treeData, err := ReadObject(treeHash)
+	if err != nil {
+		return err
+	}
+
+	tree := ParseTree(string(treeData), treeHash)
+	for _, entry := range tree.Entries {
+		if err := checkTreeEntryName(entry.Name); err != nil {
+			return fmt.Errorf("tree %s: %w", treeHash, err)
+		}
+		path := entry.Name
+		if prefix != "" {
+			path = prefix + "/" + entry.Name
+		}
+
+		if err := fn(path, entry); err != nil {
+			return err
+		}
+
+		if entry.Type == "tree" {
+			if err := WalkTree(entry.Hash, path, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindTreeEntry looks up a "/" separated path below the tree, descending
+// only into the subtrees on the way.
+func FindTreeEntry(treeHash string, path string) (TreeEntry, bool, error) {
+	entry := TreeEntry{Mode: "040000", Type: "tree", Hash: treeHash}
+
+	for _, name := range strings.Split(path, "/") {
+		if entry.Type != "tree" {
+			return TreeEntry{}, false, nil
+		}
+
+		treeData, err := ReadObject(entry.Hash)
+		if err != nil {
+			return TreeEntry{}, false, err
+		}
+
+		found := false
+		for _, child := range ParseTree(string(treeData), entry.Hash).Entries {
+			if child.Name == name {
+				entry, found = child, true
+				break
+			}
+		}
+		if !found {
+			return TreeEntry{}, false, nil
+		}
+	}
+
+	return entry, true, nil
+}
+
+// FlattenTree lists every blob and gitlink reachable from the tree keyed by
+// its path.
+func FlattenTree(treeHash string) (map[string]IndexEntry, error) {
+	files := make(map[string]IndexEntry)
+	if treeHash == "" {
+		return files, nil
+	}
+
+	err := WalkTree(treeHash, "", func(path string, entry TreeEntry) error {
+		if entry.Type != "tree" {
+			files[path] = IndexEntry{Mode: entry.Mode, Hash: entry.Hash, Path: path}
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// FlattenCommit lists the files recorded by a commit; an empty hash yields
+// no files.
+func FlattenCommit(commitHash string) (map[string]IndexEntry, error) {
+	if commitHash == "" {
+		return make(map[string]IndexEntry), nil
+	}
+
+	commitData, err := ReadObject(commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return FlattenTree(ParseCommit(string(commitData)).TreeHash)
+}
+
+// WriteBlobContent stores content as a blob and returns its hash.
+func WriteBlobContent(content []byte, objectsDir string) (string, error) {
+	blob := TreeEntry{
+		Mode:    "100644",
+		Type:    "blob",
+		Hash:    HashContent(content),
+		Content: append([]byte(fmt.Sprintf("blob %d\000", len(content))), content...),
+	}
+
+	return WriteBlob(&blob, objectsDir)
+}
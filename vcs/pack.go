@@ -0,0 +1,289 @@
+package vcs
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A pack is the stream objects travel in between repositories:
+//
+//	GTPACK <count>\n
+//	<hash> <size>\n<size bytes of the stored object, header included>
+//	...
+//	<sha1 of everything above>\n
+//
+// The receiver checks every object against its hash and the trailer against
+// the whole stream before trusting any of it.
+const packSignature = "GTPACK"
+
+// writePack streams the given objects from objectsDir to w.
+func writePack(w io.Writer, objectsDir string, hashes []string) error {
+	sum := sha1.New()
+	out := io.MultiWriter(w, sum)
+
+	if _, err := fmt.Fprintf(out, "%s %d\n", packSignature, len(hashes)); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		raw, err := readRawObject(objectsDir, hash)
+		if err != nil {
+			return fmt.Errorf("missing object %s: %w", hash, err)
+		}
+		if _, err := fmt.Fprintf(out, "%s %d\n", hash, len(raw)); err != nil {
+			return err
+		}
+		if _, err := out.Write(raw); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s\n", hex.EncodeToString(sum.Sum(nil)))
+	return err
+}
+
+// readPack reads a pack from r into objectsDir and returns the hashes of
+// the objects it held. Objects are only written once the whole stream has
+// been read and verified; with an empty objectsDir nothing is written.
+func readPack(r io.Reader, objectsDir string) ([]string, error) {
+	sum := sha1.New()
+	in := bufio.NewReader(r)
+
+	header, err := readPackLine(in, sum)
+	if err != nil {
+		return nil, err
+	}
+	signature, countText, _ := strings.Cut(header, " ")
+	count, err := strconv.Atoi(countText)
+	if signature != packSignature || err != nil || count < 0 {
+		return nil, fmt.Errorf("not a pack stream")
+	}
+
+	objects := make(map[string][]byte)
+	var hashes []string
+	for i := 0; i < count; i++ {
+		line, err := readPackLine(in, sum)
+		if err != nil {
+			return nil, err
+		}
+		hash, sizeText, _ := strings.Cut(line, " ")
+		size, err := strconv.Atoi(sizeText)
+		if err != nil || size < 0 || !isObjectHash(hash) {
+			return nil, fmt.Errorf("corrupt pack entry: %q", line)
+		}
+
+		// The size comes from the peer, so memory grows with the bytes that
+		// actually arrive rather than with what was announced.
+		raw, err := io.ReadAll(io.LimitReader(in, int64(size)))
+		if err != nil {
+			return nil, fmt.Errorf("truncated pack: %w", err)
+		}
+		if len(raw) != size {
+			return nil, fmt.Errorf("truncated pack: %w", io.ErrUnexpectedEOF)
+		}
+		sum.Write(raw)
+
+		actual, err := objectHash(raw)
+		if err != nil {
+			return nil, err
+		}
+		if actual != hash {
+			return nil, fmt.Errorf("pack object %s has hash %s", hash, actual)
+		}
+		if _, dup := objects[hash]; !dup {
+			hashes = append(hashes, hash)
+		}
+		objects[hash] = raw
+	}
+
+	expected := hex.EncodeToString(sum.Sum(nil))
+	trailer, err := in.ReadString('\n')
+	if err != nil || strings.TrimSpace(trailer) != expected {
+		return nil, fmt.Errorf("pack checksum mismatch")
+	}
+
+	if objectsDir == "" {
+		return hashes, nil
+	}
+	return hashes, writeObjectsInOrder(objects, objectsDir)
+}
+
+func readPackLine(in *bufio.Reader, sum hash.Hash) (string, error) {
+	line, err := in.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("truncated pack: %w", err)
+	}
+	sum.Write([]byte(line))
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// objectHash computes the hash an object is stored under from its stored
+// bytes. Blobs and trees are hashed without their header, commits and tags
+// with it.
+func objectHash(raw []byte) (string, error) {
+	objType, data, err := parseObject(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if objType == "blob" || objType == "tree" {
+		return HashContent(data), nil
+	}
+	return HashContent(raw), nil
+}
+
+// packObjects lists the objects in objectsDir reachable from wants that a
+// repository holding haves is missing. Commits reachable from a known have
+// are skipped entirely, as are the trees and blobs of the haves themselves,
+// so only unchanged files of older commits may be sent twice. opts can cut
+// the history of each want short and leave out blobs found in trees. Any
+// other missing object is an error, except for the parents of the
+// repository's own shallow boundary.
+func packObjects(objectsDir string, wants []string, haves []string, opts FetchOptions) ([]string, error) {
+	shallow, err := readShallowIn(filepath.Dir(objectsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	common := make(map[string]bool)
+	for _, have := range haves {
+		if !objectExistsIn(objectsDir, have) {
+			continue
+		}
+		if err := markReachable(objectsDir, have, common, true); err != nil {
+			return nil, err
+		}
+	}
+
+	type packItem struct {
+		hash  string
+		depth int // Commits between this object and the want it came from
+	}
+
+	var objects []string
+	seen := make(map[string]bool)
+	var pending []packItem
+	for _, want := range wants {
+		pending = append(pending, packItem{hash: want})
+	}
+
+	for len(pending) > 0 {
+		item := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if item.hash == "" || seen[item.hash] || common[item.hash] {
+			continue
+		}
+		seen[item.hash] = true
+
+		raw, err := readRawObject(objectsDir, item.hash)
+		if err != nil {
+			return nil, fmt.Errorf("missing object %s: %w", item.hash, err)
+		}
+		objType, data, err := parseObject(raw)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, item.hash)
+
+		switch objType {
+		case "commit":
+			commit := ParseCommit(string(data))
+			pending = append(pending, packItem{hash: commit.TreeHash, depth: item.depth})
+			// A shallow repository has no parents for its boundary commits.
+			if shallow[item.hash] {
+				continue
+			}
+			if opts.Depth == 0 || item.depth+1 < opts.Depth {
+				pending = append(pending, packItem{hash: commit.ParentHash, depth: item.depth + 1})
+			}
+		case "tree":
+			for _, entry := range ParseTree(string(data), item.hash).Entries {
+				// A partial clone leaves blobs out.
+				if entry.Type == "blob" && opts.BlobNone {
+					continue
+				}
+				// Gitlinks point into another repository.
+				if entry.Type == "commit" {
+					continue
+				}
+				pending = append(pending, packItem{hash: entry.Hash, depth: item.depth})
+			}
+		default:
+			for _, ref := range objectReferences(objType, data) {
+				pending = append(pending, packItem{hash: ref, depth: item.depth})
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// markReachable adds hash to marked. For a tag or commit it follows the
+// chain of parents as far as it is stored, which a shallow repository cuts
+// short; withTree also marks everything in the first commit's tree.
+func markReachable(objectsDir string, hash string, marked map[string]bool, withTree bool) error {
+	for hash != "" && !marked[hash] && objectExistsIn(objectsDir, hash) {
+		marked[hash] = true
+
+		raw, err := readRawObject(objectsDir, hash)
+		if err != nil {
+			return err
+		}
+		objType, data, err := parseObject(raw)
+		if err != nil {
+			return err
+		}
+
+		switch objType {
+		case "tag":
+			hash = ParseTag(string(data)).Object
+		case "commit":
+			commit := ParseCommit(string(data))
+			if withTree {
+				if err := markTree(objectsDir, commit.TreeHash, marked); err != nil {
+					return err
+				}
+				withTree = false
+			}
+			hash = commit.ParentHash
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func markTree(objectsDir string, hash string, marked map[string]bool) error {
+	if marked[hash] {
+		return nil
+	}
+	marked[hash] = true
+
+	raw, err := readRawObject(objectsDir, hash)
+	if err != nil {
+		return err
+	}
+	_, data, err := parseObject(raw)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range ParseTree(string(data), hash).Entries {
+		switch entry.Type {
+		case "tree":
+			if err := markTree(objectsDir, entry.Hash, marked); err != nil {
+				return err
+			}
+		case "blob":
+			marked[entry.Hash] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"strings"
+)
+
+// HashObject returns the blob hash of content, storing the blob when write
+// is set.
+func HashObject(content []byte, write bool) (string, error) {
+	if !write {
+		return HashContent(content), nil
+	}
+	return WriteBlobContent(content, constants.ObjectsDir)
+}
+
+// CommitTree stores a commit of the tree tree names with the given parent,
+// which may be empty for a root commit, and returns its hash. Unlike
+// CommitIndex it moves no refs.
+func CommitTree(tree string, parent string, message string) (string, error) {
+	hash, err := ResolveObject(tree)
+	if err != nil {
+		return "", err
+	}
+	treeHash, err := peelToTree(hash)
+	if err != nil {
+		return "", err
+	}
+
+	parentHash := ""
+	if parent != "" {
+		if parentHash, err = ResolveRevision(parent); err != nil {
+			return "", err
+		}
+	}
+
+	commit := WriteCommit(treeHash, parentHash, GetIdentity(), message, constants.ObjectsDir)
+	return commit.Hash, nil
+}
+
+// CompareAndSwapRef points ref, "HEAD" or a full name such as
+// "refs/heads/main", at the object newRev names, but only while it still
+// holds oldRev. An empty oldRev skips the check; ZeroHash requires the ref
+// not to exist yet. The move is recorded in the reflog with reason.
+func CompareAndSwapRef(ref string, newRev string, oldRev string, reason string) error {
+	if ref != "HEAD" {
+		if !strings.HasPrefix(ref, "refs/") {
+			return fmt.Errorf("refusing to update ref outside refs/: %s", ref)
+		}
+		if err := CheckRefName(strings.TrimPrefix(ref, "refs/")); err != nil {
+			return err
+		}
+	}
+
+	newHash, err := ResolveObject(newRev)
+	if err != nil {
+		return err
+	}
+
+	if oldRev == "" {
+		return UpdateRef(ref, newHash, reason)
+	}
+	oldHash, err := expectedRefValue(oldRev)
+	if err != nil {
+		return err
+	}
+	return UpdateRefFrom(ref, newHash, oldHash, reason)
+}
+
+// expectedRefValue resolves the old value given to CompareAndSwapRef, with
+// ZeroHash standing for a missing ref. A full hash is taken as is, so it
+// need not name a stored object.
+func expectedRefValue(rev string) (string, error) {
+	if rev == ZeroHash {
+		return "", nil
+	}
+	if isObjectHash(rev) {
+		return rev, nil
+	}
+	return ResolveObject(rev)
+}
@@ -0,0 +1,94 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+)
+
+// A partial clone made with --filter=blob:none leaves file contents on the
+// remote, which is marked as a promisor in .gt/config. Reading an object
+// that is not stored fetches it from there.
+
+func markPromisor(name string) error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	config.Set("remote."+name+".promisor", "true")
+	config.Set("remote."+name+".partialclonefilter", "blob:none")
+	return config.Write()
+}
+
+// promisorRemote returns the remote missing objects can be fetched from,
+// if this is a partial clone.
+func promisorRemote() (Remote, bool, error) {
+	remotes, err := ListRemotes()
+	if err != nil {
+		return Remote{}, false, err
+	}
+	for _, remote := range remotes {
+		if remote.Promisor {
+			return remote, true, nil
+		}
+	}
+	return Remote{}, false, nil
+}
+
+// fetchMissingObject fetches a single object left out by a partial clone
+// and reports whether it is now stored.
+func fetchMissingObject(hash string) (bool, error) {
+	remote, ok, err := promisorRemote()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := fetchPromisedObjects(remote, []string{hash}); err != nil {
+		return false, fmt.Errorf("fetching %s from %s: %w", hash, remote.Name, err)
+	}
+	return ObjectExists(hash), nil
+}
+
+// prefetchBlobs fetches the blobs of files that are missing and would be
+// checked out, so a partial clone gets them in one request.
+func prefetchBlobs(remote Remote, files map[string]IndexEntry) error {
+	if !remote.Promisor {
+		return nil
+	}
+
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, path := range sortedPaths(files) {
+		hash := files[path].Hash
+		if files[path].Mode != GitlinkMode && sparse.Includes(path) && !ObjectExists(hash) {
+			missing = append(missing, hash)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fetchPromisedObjects(remote, missing)
+}
+
+// fetchPromisedObjects asks remote for the given objects only. Commits come
+// without their history and trees without their blobs.
+func fetchPromisedObjects(remote Remote, hashes []string) error {
+	t, err := openTransport(remote.URL)
+	if err != nil {
+		return err
+	}
+	return t.fetchObjects(hashes, nil, FetchOptions{Depth: 1, BlobNone: true})
+}
+
+// readStoredObject reads an object from .gt/objects without fetching it
+// when it is missing.
+func readStoredObject(hash string) (string, []byte, error) {
+	data, err := readRawObject(constants.ObjectsDir, hash)
+	if err != nil {
+		return "", nil, err
+	}
+	return parseObject(data)
+}
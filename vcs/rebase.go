@@ -0,0 +1,500 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// rebaseState is what .gt/rebase-merge holds while a rebase is in progress.
+// It is rewritten after every step so a crashed rebase can be continued.
+type rebaseState struct {
+	HeadName  string   // Branch being rebased, "" when HEAD was detached
+	OrigHead  string   // Commit HEAD pointed at before the rebase
+	Onto      string   // Commit the todo list is replayed onto
+	Todo      []string // Remaining steps, current one first
+	Done      []string // Finished steps
+	Conflicts []string // Paths left with conflict markers by the current step
+	Message   string   // Commit message for the current step
+	Author    string   // Author for the current step's commit
+}
+
+// Actions accepted in a rebase todo list, with their one letter forms.
+var rebaseActions = map[string]string{
+	"pick": "pick", "p": "pick",
+	"reword": "reword", "r": "reword",
+	"squash": "squash", "s": "squash",
+	"fixup": "fixup", "f": "fixup",
+	"drop": "drop", "d": "drop",
+	"exec": "exec", "x": "exec",
+}
+
+func rebaseInProgress() bool {
+	_, err := os.Stat(repoPath(constants.RebaseDir))
+	return err == nil
+}
+
+func readRebaseState() (rebaseState, error) {
+	if !rebaseInProgress() {
+		return rebaseState{}, fmt.Errorf("no rebase in progress")
+	}
+
+	files := make(map[string]string)
+	for _, name := range []string{"head-name", "orig-head", "onto", "todo", "done", "conflicts", "message", "author"} {
+		data, err := os.ReadFile(repoPath(filepath.Join(constants.RebaseDir, name)))
+		if err != nil && !os.IsNotExist(err) {
+			return rebaseState{}, err
+		}
+		files[name] = string(data)
+	}
+
+	return rebaseState{
+		HeadName:  strings.TrimSpace(files["head-name"]),
+		OrigHead:  strings.TrimSpace(files["orig-head"]),
+		Onto:      strings.TrimSpace(files["onto"]),
+		Todo:      nonEmptyLines(files["todo"]),
+		Done:      nonEmptyLines(files["done"]),
+		Conflicts: nonEmptyLines(files["conflicts"]),
+		Message:   files["message"],
+		Author:    strings.TrimSpace(files["author"]),
+	}, nil
+}
+
+func writeRebaseState(state rebaseState) error {
+	if err := os.MkdirAll(repoPath(constants.RebaseDir), 0755); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"head-name": state.HeadName + "\n",
+		"orig-head": state.OrigHead + "\n",
+		"onto":      state.Onto + "\n",
+		"todo":      joinLines(state.Todo),
+		"done":      joinLines(state.Done),
+		"conflicts": joinLines(state.Conflicts),
+		"message":   state.Message,
+		"author":    state.Author + "\n",
+	}
+	for name, content := range files {
+		if err := writeFileAtomic(filepath.Join(constants.RebaseDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RebaseTodo returns the default todo list for rebasing HEAD onto upstream:
+// a pick line for every commit not in upstream, oldest first.
+func RebaseTodo(upstream string) ([]string, error) {
+	upstreamHash, err := ResolveRevision(upstream)
+	if err != nil {
+		return nil, err
+	}
+	_, head, err := ReadHead()
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := RevList(head, upstreamHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var todo []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit, err := ReadCommit(commits[i])
+		if err != nil {
+			return nil, err
+		}
+		todo = append(todo, fmt.Sprintf("pick %s %s", commits[i], firstLine(commit.Message)))
+	}
+
+	return todo, nil
+}
+
+// ParseRebaseTodo reads a todo list, one "<action> <rev> [text]" or
+// "exec <command>" per line, and returns it with full hashes. For reword the
+// text is the new commit message; for the other actions it is ignored.
+func ParseRebaseTodo(data string) ([]string, error) {
+	var todo []string
+
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		word, rest, _ := strings.Cut(line, " ")
+		action, ok := rebaseActions[word]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown action '%s'", n+1, word)
+		}
+		rest = strings.TrimSpace(rest)
+
+		if action == "exec" {
+			if rest == "" {
+				return nil, fmt.Errorf("line %d: exec needs a command", n+1)
+			}
+			todo = append(todo, "exec "+rest)
+			continue
+		}
+
+		rev, text, _ := strings.Cut(rest, " ")
+		if rev == "" {
+			return nil, fmt.Errorf("line %d: %s needs a commit", n+1, action)
+		}
+		hash, err := ResolveRevision(rev)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		switch action {
+		case "reword":
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return nil, fmt.Errorf("line %d: reword needs the new message", n+1)
+			}
+			todo = append(todo, "reword "+hash+" "+text)
+		case "squash", "fixup":
+			if len(todo) == 0 {
+				return nil, fmt.Errorf("line %d: cannot %s without a previous commit", n+1, action)
+			}
+			todo = append(todo, action+" "+hash)
+		default:
+			todo = append(todo, action+" "+hash)
+		}
+	}
+
+	return todo, nil
+}
+
+// StartRebase replays the commits of branch (HEAD when empty) that are not in
+// upstream onto onto (upstream when empty). A todoFile replaces the default
+// list of picks.
+func StartRebase(upstream string, onto string, branch string, todoFile string) error {
+	if rebaseInProgress() {
+		return fmt.Errorf("a rebase is already in progress; use --continue, --skip or --abort")
+	}
+	if sequencerInProgress() {
+		return fmt.Errorf("a revert or cherry-pick is in progress; finish or abort it first")
+	}
+
+	if err := requireCleanTree(); err != nil {
+		return err
+	}
+
+	if branch != "" {
+		if !BranchExists(branch) {
+			return fmt.Errorf("branch '%s' not found", branch)
+		}
+		if err := checkoutCommit("refs/heads/"+branch, "rebase: checkout "+branch); err != nil {
+			return err
+		}
+	}
+
+	if onto == "" {
+		onto = upstream
+	}
+	ontoHash, err := ResolveRevision(onto)
+	if err != nil {
+		return err
+	}
+
+	var todo []string
+	if todoFile != "" {
+		data, err := os.ReadFile(todoFile)
+		if err != nil {
+			return err
+		}
+		if todo, err = ParseRebaseTodo(string(data)); err != nil {
+			return err
+		}
+	} else {
+		lines, err := RebaseTodo(upstream)
+		if err != nil {
+			return err
+		}
+		if todo, err = ParseRebaseTodo(strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+
+	symref, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	state := rebaseState{HeadName: symref, OrigHead: head, Onto: ontoHash, Todo: todo}
+	if err := writeRebaseState(state); err != nil {
+		return err
+	}
+
+	if err := checkoutCommit(ontoHash, "rebase (start): checkout "+onto); err != nil {
+		return err
+	}
+
+	return runRebase(state)
+}
+
+// ContinueRebase finishes the step that stopped, after conflicts were
+// resolved or a failed exec was fixed, and carries on.
+func ContinueRebase() error {
+	state, err := readRebaseState()
+	if err != nil {
+		return err
+	}
+
+	if len(state.Conflicts) > 0 {
+		if err := stageResolved(state.Conflicts); err != nil {
+			return err
+		}
+		action, _, _ := strings.Cut(state.Todo[0], " ")
+		if err := commitRebaseStep(state, action); err != nil {
+			return err
+		}
+		if err := popRebaseStep(&state); err != nil {
+			return err
+		}
+	}
+
+	return runRebase(state)
+}
+
+// SkipRebase drops the step that stopped and carries on with the next one.
+func SkipRebase() error {
+	state, err := readRebaseState()
+	if err != nil {
+		return err
+	}
+	if len(state.Todo) == 0 {
+		return fmt.Errorf("nothing to skip")
+	}
+
+	if err := discardStep(state.Conflicts, "HEAD"); err != nil {
+		return err
+	}
+	if err := popRebaseStep(&state); err != nil {
+		return err
+	}
+
+	return runRebase(state)
+}
+
+// AbortRebase returns to the original branch and commit.
+func AbortRebase() error {
+	state, err := readRebaseState()
+	if err != nil {
+		return err
+	}
+
+	// HEAD is detached during the rebase, so this only moves HEAD.
+	if err := discardStep(state.Conflicts, state.OrigHead); err != nil {
+		return err
+	}
+	if state.HeadName != "" {
+		if err := SetHead(state.HeadName, "rebase (abort): returning to "+state.HeadName); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(repoPath(constants.RebaseDir))
+}
+
+func popRebaseStep(state *rebaseState) error {
+	state.Done = append(state.Done, state.Todo[0])
+	state.Todo = state.Todo[1:]
+	state.Conflicts = nil
+	state.Message = ""
+	state.Author = ""
+	return writeRebaseState(*state)
+}
+
+func runRebase(state rebaseState) error {
+	for len(state.Todo) > 0 {
+		action, rest, _ := strings.Cut(state.Todo[0], " ")
+
+		switch action {
+		case "drop":
+			if err := popRebaseStep(&state); err != nil {
+				return err
+			}
+			continue
+		case "exec":
+			// The step counts as done even if the command fails, so that
+			// --continue does not run it again.
+			if err := popRebaseStep(&state); err != nil {
+				return err
+			}
+			fmt.Println("Executing:", rest)
+			cmd := exec.Command("sh", "-c", rest)
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("exec '%s' failed: %v; fix it and run 'gt rebase --continue'", rest, err)
+			}
+			continue
+		}
+
+		hash, text, _ := strings.Cut(rest, " ")
+		commit, err := ReadCommit(hash)
+		if err != nil {
+			return err
+		}
+		_, head, err := ReadHead()
+		if err != nil {
+			return err
+		}
+
+		// A pick right on top of its own parent keeps the commit as is.
+		if action == "pick" && commit.ParentHash == head {
+			if err := checkoutCommit(hash, "rebase (pick): "+firstLine(commit.Message)); err != nil {
+				return err
+			}
+			if err := popRebaseStep(&state); err != nil {
+				return err
+			}
+			continue
+		}
+
+		state.Message, state.Author = commit.Message, commit.Author
+		switch action {
+		case "reword":
+			state.Message = text
+		case "squash", "fixup":
+			previous, err := ReadCommit(head)
+			if err != nil {
+				return err
+			}
+			state.Message, state.Author = previous.Message, previous.Author
+			if action == "squash" {
+				state.Message += "\n\n" + commit.Message
+			}
+		}
+
+		_, _, conflicts, err := applyStep("pick", hash)
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) > 0 {
+			state.Conflicts = conflicts
+			if err := writeRebaseState(state); err != nil {
+				return err
+			}
+			for _, path := range conflicts {
+				fmt.Printf("CONFLICT: Merge conflict in %s\n", path)
+			}
+			return fmt.Errorf("could not apply %s (%s); resolve the conflicts and run 'gt rebase --continue', or use --skip or --abort",
+				hash[:7], firstLine(commit.Message))
+		}
+
+		if err := commitRebaseStep(state, action); err != nil {
+			return err
+		}
+		if err := popRebaseStep(&state); err != nil {
+			return err
+		}
+	}
+
+	return finishRebase(state)
+}
+
+// commitRebaseStep records the applied step: a new commit for pick and
+// reword, an amended one for squash and fixup.
+func commitRebaseStep(state rebaseState, action string) error {
+	if action == "squash" || action == "fixup" {
+		treeHash, err := WriteIndexTree()
+		if err != nil {
+			return err
+		}
+		_, err = AmendCommit(treeHash, state.Message, state.Author, "rebase ("+action+")")
+		return err
+	}
+
+	_, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	headFiles, err := FlattenCommit(head)
+	if err != nil {
+		return err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+	if BuildTreeFromIndex(index).Hash == BuildTreeFromIndex(headFiles).Hash {
+		fmt.Printf("Dropping '%s', its changes are already upstream.\n", firstLine(state.Message))
+		return nil
+	}
+
+	author := state.Author
+	if author == "" {
+		author = GetIdentity()
+	}
+	_, err = CommitIndex(state.Message, author, "rebase ("+action+")")
+	return err
+}
+
+func finishRebase(state rebaseState) error {
+	_, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	if state.HeadName != "" {
+		if err := UpdateRef(state.HeadName, head, "rebase (finish): "+state.HeadName+" onto "+state.Onto); err != nil {
+			return err
+		}
+		if err := SetHead(state.HeadName, "rebase (finish): returning to "+state.HeadName); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Successfully rebased and updated", describeHead(state.HeadName, head))
+	return os.RemoveAll(repoPath(constants.RebaseDir))
+}
+
+func describeHead(headName string, hash string) string {
+	if headName == "" {
+		return "detached HEAD at " + hash[:7]
+	}
+	return headName
+}
+
+// checkoutCommit points HEAD at target, a branch ref or a commit hash to
+// detach at, and brings the index and tracked files along.
+func checkoutCommit(target string, reason string) error {
+	hash := target
+	if strings.HasPrefix(target, "refs/") {
+		var err error
+		if hash, err = ReadRef(target); err != nil {
+			return err
+		}
+	}
+
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+	files, err := FlattenCommit(hash)
+	if err != nil {
+		return err
+	}
+
+	if err := checkUntrackedOverwrite(index, files); err != nil {
+		return err
+	}
+
+	if err := SetHead(target, reason); err != nil {
+		return err
+	}
+	if err := UpdateWorkingTree(index, files); err != nil {
+		return err
+	}
+
+	return WriteIndex(files)
+}
@@ -0,0 +1,262 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZeroHash stands for "no commit" on either side of a reflog entry.
+const ZeroHash = "0000000000000000000000000000000000000000"
+
+// ReflogEntry is a single line of .gt/logs/<ref>
+type ReflogEntry struct {
+	OldHash   string
+	NewHash   string
+	Identity  string
+	TimeStamp int64
+	Zone      string
+	Reason    string
+}
+
+// UpdateRef points ref at newHash and appends the move to its reflog.
+// "HEAD" updates the branch HEAD points at, or HEAD itself when detached.
+func UpdateRef(ref string, newHash string, reason string) error {
+	return updateRef(ref, newHash, "", false, reason)
+}
+
+// UpdateRefFrom is UpdateRef that only moves ref while it still points at
+// oldHash, so a concurrent update is not silently lost. An empty oldHash
+// requires the ref not to exist yet, or HEAD to have no commit.
+func UpdateRefFrom(ref string, newHash string, oldHash string, reason string) error {
+	return updateRef(ref, newHash, oldHash, true, reason)
+}
+
+// updateRef holds the ref's lock from reading the old value until the new
+// one is renamed into place.
+func updateRef(ref string, newHash string, expected string, check bool, reason string) error {
+	symref, _, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	name := ref
+	if ref == "HEAD" && symref != "" {
+		name = symref
+	}
+
+	refPath := repoPath(filepath.Join(constants.GTDir, name))
+	if name != "HEAD" {
+		if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+			return err
+		}
+	}
+	lock, err := acquireLock(refPath)
+	if err != nil {
+		return err
+	}
+
+	var oldHash string
+	if name == "HEAD" {
+		_, oldHash, err = ReadHead()
+	} else {
+		oldHash, err = ReadRef(name)
+	}
+	if err != nil {
+		lock.rollback()
+		return err
+	}
+	if check && oldHash != expected {
+		lock.rollback()
+		if oldHash == "" {
+			oldHash = "nothing"
+		}
+		if expected == "" {
+			expected = "nothing"
+		}
+		return fmt.Errorf("cannot update %s: expected %s but it points at %s", ref, expected, oldHash)
+	}
+
+	if err := lock.commit([]byte(newHash + "\n")); err != nil {
+		return err
+	}
+
+	if err := appendReflog(name, oldHash, newHash, reason); err != nil {
+		return err
+	}
+
+	// Moving the checked out branch also moves HEAD.
+	if name == symref {
+		return appendReflog("HEAD", oldHash, newHash, reason)
+	}
+
+	return nil
+}
+
+// SetHead makes HEAD point at target, which is either a branch ref such as
+// "refs/heads/main" or a commit hash to detach at. A branch checked out in
+// another worktree cannot be checked out here too.
+func SetHead(target string, reason string) error {
+	_, oldHash, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	newHash := target
+	if strings.HasPrefix(target, "refs/") {
+		admin, err := currentAdmin()
+		if err != nil {
+			return err
+		}
+		other, err := checkedOutElsewhere(repoPath(constants.GTDir), target, admin)
+		if err != nil {
+			return err
+		}
+		if other != "" {
+			return fmt.Errorf("'%s' is already checked out at '%s'", strings.TrimPrefix(target, "refs/heads/"), other)
+		}
+
+		if newHash, err = ReadRef(target); err != nil {
+			return err
+		}
+		target = "ref: " + target
+	}
+
+	if err := writeHead(target); err != nil {
+		return err
+	}
+
+	return appendReflog("HEAD", oldHash, newHash, reason)
+}
+
+func writeHead(content string) error {
+	lock, err := acquireLock(repoPath(filepath.Join(constants.GTDir, "HEAD")))
+	if err != nil {
+		return err
+	}
+	return lock.commit([]byte(content + "\n"))
+}
+
+func appendReflog(ref string, oldHash string, newHash string, reason string) error {
+	if oldHash == "" {
+		oldHash = ZeroHash
+	}
+	if newHash == "" {
+		newHash = ZeroHash
+	}
+
+	logPath := repoPath(filepath.Join(constants.LogsDir, ref))
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	now := time.Now()
+	reason = strings.ReplaceAll(reason, "\n", " ")
+	line := fmt.Sprintf("%s %s %s %d %s\t%s\n",
+		oldHash, newHash, GetIdentity(), now.Unix(), now.Format("-0700"), reason)
+
+	_, err = logFile.WriteString(line)
+	return err
+}
+
+// ReadReflog returns the entries recorded for ref, oldest first.
+func ReadReflog(ref string) ([]ReflogEntry, error) {
+	data, err := os.ReadFile(repoPath(filepath.Join(constants.LogsDir, ref)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []ReflogEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		entry, err := parseReflogLine(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseReflogLine(line string) (ReflogEntry, error) {
+	header, reason, _ := strings.Cut(line, "\t")
+	fields := strings.Fields(header)
+	if len(fields) < 5 {
+		return ReflogEntry{}, fmt.Errorf("malformed reflog line: %q", line)
+	}
+
+	timestamp, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return ReflogEntry{}, fmt.Errorf("malformed reflog line: %q", line)
+	}
+
+	return ReflogEntry{
+		OldHash:   fields[0],
+		NewHash:   fields[1],
+		Identity:  strings.Join(fields[2:len(fields)-2], " "),
+		TimeStamp: timestamp,
+		Zone:      fields[len(fields)-1],
+		Reason:    reason,
+	}, nil
+}
+
+// ReflogRef maps the name used in "<name>@{n}" to the ref whose log it reads.
+func ReflogRef(name string) string {
+	if name == "" || name == "HEAD" {
+		return "HEAD"
+	}
+	if strings.HasPrefix(name, "refs/") {
+		return name
+	}
+	return "refs/heads/" + name
+}
+
+// parseReflogSelector splits "main@{3}" into "main" and 3.
+func parseReflogSelector(rev string) (string, int, bool) {
+	open := strings.Index(rev, "@{")
+	if open == -1 || !strings.HasSuffix(rev, "}") {
+		return "", 0, false
+	}
+
+	n, err := strconv.Atoi(rev[open+2 : len(rev)-1])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+
+	return rev[:open], n, true
+}
+
+func resolveReflogEntry(name string, n int) (string, error) {
+	ref := ReflogRef(name)
+	entries, err := ReadReflog(ref)
+	if err != nil {
+		return "", err
+	}
+
+	if n >= len(entries) {
+		return "", fmt.Errorf("log for '%s' only has %d entries", ref, len(entries))
+	}
+
+	hash := entries[len(entries)-1-n].NewHash
+	if hash == ZeroHash {
+		return "", fmt.Errorf("%s@{%d} does not point to a commit", ref, n)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,355 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadHead returns the ref HEAD points at ("" when HEAD is detached) and the
+// commit hash it resolves to ("" before the first commit).
+func ReadHead() (string, string, error) {
+	return readHeadIn(constants.GTDir)
+}
+
+func readHeadIn(gtDir string) (string, string, error) {
+	data, err := os.ReadFile(repoPath(filepath.Join(gtDir, "HEAD")))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil // No commits yet
+		}
+		return "", "", err
+	}
+
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		hash, err := readRefIn(gtDir, ref)
+		return ref, hash, err
+	}
+
+	return "", head, nil
+}
+
+// ReadRef returns the hash stored in a ref such as "refs/tags/v1.0".
+// A missing ref is not an error, it resolves to "".
+func ReadRef(ref string) (string, error) {
+	return readRefIn(constants.GTDir, ref)
+}
+
+func readRefIn(gtDir string, ref string) (string, error) {
+	data, err := os.ReadFile(repoPath(filepath.Join(gtDir, ref)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func WriteRef(ref string, hash string) error {
+	return writeRefIn(constants.GTDir, ref, hash)
+}
+
+func writeRefIn(gtDir string, ref string, hash string) error {
+	refPath := repoPath(filepath.Join(gtDir, ref))
+
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+		return err
+	}
+	lock, err := acquireLock(refPath)
+	if err != nil {
+		return err
+	}
+
+	// Renaming a complete file into place means readers never see a
+	// half-written ref.
+	return lock.commit([]byte(hash + "\n"))
+}
+
+func DeleteRef(ref string) error {
+	refPath := repoPath(filepath.Join(constants.GTDir, ref))
+	lock, err := acquireLock(refPath)
+	if err != nil {
+		return err
+	}
+	defer lock.rollback()
+
+	return os.Remove(refPath)
+}
+
+// ListRefs returns the names of all refs below prefix (e.g. "refs/tags"),
+// relative to that prefix and sorted.
+func ListRefs(prefix string) ([]string, error) {
+	return listRefsIn(constants.GTDir, prefix)
+}
+
+func listRefsIn(gtDir string, prefix string) ([]string, error) {
+	root := repoPath(filepath.Join(gtDir, prefix))
+	var names []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// Locks held by a ref update are not refs.
+		if info.IsDir() || strings.HasSuffix(info.Name(), ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// CheckRefName rejects names that cannot be stored as a ref file.
+func CheckRefName(name string) error {
+	if name == "" || name == "HEAD" || strings.HasPrefix(name, "-") ||
+		strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+		strings.HasSuffix(name, ".lock") || strings.Contains(name, "..") ||
+		strings.Contains(name, "//") || strings.Contains(name, "@{") ||
+		strings.ContainsAny(name, " \t\n~^:?*[\\") {
+		return fmt.Errorf("invalid ref name: %q", name)
+	}
+	return nil
+}
+
+// ResolveObject turns a revision (hash, unique hash prefix, HEAD, ref, tag,
+// branch or remote-tracking branch name, or a reflog selector such as
+// HEAD@{2}, optionally followed by ~n / ^ ancestor suffixes) into an object
+// hash without peeling annotated tags. "<rev>:<path>" names the blob or
+// tree at path in the commit, "<rev>:" its root tree and ":<path>" the blob
+// staged for path.
+func ResolveObject(rev string) (string, error) {
+	if rev == "" {
+		return "", fmt.Errorf("empty revision")
+	}
+
+	if base, path, ok := strings.Cut(rev, ":"); ok {
+		return resolveTreePath(base, path)
+	}
+
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		return resolveAncestor(rev[:i], rev[i:])
+	}
+
+	if base, n, ok := parseReflogSelector(rev); ok {
+		return resolveReflogEntry(base, n)
+	}
+
+	if rev == "HEAD" {
+		hash, err := GetLatestCommitHash()
+		if err != nil {
+			return "", err
+		}
+		if hash == "" {
+			return "", fmt.Errorf("HEAD does not point to a commit yet")
+		}
+		return hash, nil
+	}
+
+	for _, ref := range []string{rev, "refs/" + rev, "refs/tags/" + rev, "refs/heads/" + rev, "refs/remotes/" + rev} {
+		// A name that cannot be a ref, such as one with ".." in it, must not
+		// be looked up as a path below .gt.
+		if !strings.HasPrefix(ref, "refs/") || CheckRefName(rev) != nil {
+			continue
+		}
+		hash, err := ReadRef(ref)
+		if err != nil {
+			return "", err
+		}
+		if hash != "" {
+			return hash, nil
+		}
+	}
+
+	if isHex(rev) {
+		return expandHash(rev)
+	}
+
+	return "", fmt.Errorf("unknown revision: %s", rev)
+}
+
+// ResolveRevision resolves rev like ResolveObject and peels annotated tags
+// down to the commit they point at.
+func ResolveRevision(rev string) (string, error) {
+	hash, err := ResolveObject(rev)
+	if err != nil {
+		return "", err
+	}
+	return PeelToCommit(hash)
+}
+
+// PeelToCommit follows tag objects until it reaches a commit.
+func PeelToCommit(hash string) (string, error) {
+	for {
+		objType, data, err := ReadObjectType(hash)
+		if err != nil {
+			return "", err
+		}
+
+		switch objType {
+		case "commit":
+			return hash, nil
+		case "tag":
+			hash = ParseTag(string(data)).Object
+		default:
+			return "", fmt.Errorf("%s is a %s, not a commit", hash, objType)
+		}
+	}
+}
+
+// resolveTreePath looks up path in the tree of the commit named by base, or
+// in the index when base is empty.
+func resolveTreePath(base string, path string) (string, error) {
+	if base == "" {
+		index, err := ReadIndex()
+		if err != nil {
+			return "", err
+		}
+		entry, ok := index[cleanPathspec(path)]
+		if !ok {
+			return "", fmt.Errorf("path '%s' is not in the index", path)
+		}
+		return entry.Hash, nil
+	}
+
+	hash, err := ResolveRevision(base)
+	if err != nil {
+		return "", err
+	}
+	commit, err := ReadCommit(hash)
+	if err != nil {
+		return "", err
+	}
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return commit.TreeHash, nil
+	}
+	entry, found, err := FindTreeEntry(commit.TreeHash, path)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("path '%s' does not exist in '%s'", path, base)
+	}
+	return entry.Hash, nil
+}
+
+// resolveAncestor applies suffixes such as "~2^" to the commit named by base.
+func resolveAncestor(base string, suffix string) (string, error) {
+	hash, err := ResolveRevision(base)
+	if err != nil {
+		return "", err
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		digits := 1
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+
+		n := 1
+		if digits > 1 {
+			if n, err = strconv.Atoi(suffix[1:digits]); err != nil {
+				return "", fmt.Errorf("invalid revision suffix: %s", suffix)
+			}
+		}
+		suffix = suffix[digits:]
+
+		switch op {
+		case '^':
+			// Commits have a single parent, so ^0 is the commit itself and
+			// ^1 its parent.
+			if n > 1 {
+				return "", fmt.Errorf("%s has no parent %d", hash, n)
+			}
+		case '~':
+		default:
+			return "", fmt.Errorf("invalid revision suffix: %c", op)
+		}
+
+		for ; n > 0; n-- {
+			commit, err := ReadCommit(hash)
+			if err != nil {
+				return "", err
+			}
+			if commit.ParentHash == "" {
+				return "", fmt.Errorf("%s has no parent", hash)
+			}
+			if shallow, err := ReadShallow(); err == nil && shallow[hash] {
+				return "", fmt.Errorf("the parent of %s was not fetched in this shallow clone", hash)
+			}
+			hash = commit.ParentHash
+		}
+	}
+
+	return hash, nil
+}
+
+// expandHash resolves a full hash or a unique prefix of at least four characters.
+func expandHash(prefix string) (string, error) {
+	if len(prefix) < 4 {
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	}
+	if len(prefix) == 40 {
+		if !ObjectExists(prefix) {
+			return "", fmt.Errorf("object %s not found", prefix)
+		}
+		return prefix, nil
+	}
+
+	entries, err := os.ReadDir(repoPath(filepath.Join(constants.ObjectsDir, prefix[:2])))
+	if err != nil {
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix[2:]) {
+			matches = append(matches, prefix[:2]+entry.Name())
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous hash prefix: %s", prefix)
+	}
+}
+
+// isObjectHash reports whether s is a full hash as objects are stored under.
+func isObjectHash(s string) bool {
+	return len(s) == len(ZeroHash) && isHex(s)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,560 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Remote is a repository configured under [remote "<name>"] in .gt/config.
+type Remote struct {
+	Name     string
+	URL      string // Repository path, bundle file or http(s) URL of gt serve
+	Promisor bool   // A partial clone fetches missing blobs from it on demand
+}
+
+// ListRemotes returns the configured remotes in the order they were added.
+func ListRemotes() ([]Remote, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []Remote
+	for _, name := range config.Subsections("remote") {
+		remotes = append(remotes, Remote{
+			Name:     name,
+			URL:      config.Get("remote." + name + ".url"),
+			Promisor: config.Get("remote."+name+".promisor") == "true",
+		})
+	}
+	return remotes, nil
+}
+
+// GetRemote looks up a configured remote by name.
+func GetRemote(name string) (Remote, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return Remote{}, err
+	}
+
+	url := config.Get("remote." + name + ".url")
+	if url == "" {
+		return Remote{}, fmt.Errorf("no such remote: '%s'", name)
+	}
+	return Remote{Name: name, URL: url, Promisor: config.Get("remote."+name+".promisor") == "true"}, nil
+}
+
+// AddRemote records a remote. Relative paths are stored as absolute ones so
+// the remote keeps working from any directory; http(s) URLs are kept as is.
+func AddRemote(name string, url string) error {
+	if err := CheckRefName(name); err != nil {
+		return err
+	}
+
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	if config.Get("remote."+name+".url") != "" {
+		return fmt.Errorf("remote %s already exists", name)
+	}
+
+	if !isHTTPURL(url) {
+		if url, err = filepath.Abs(url); err != nil {
+			return err
+		}
+		if _, err := openTransport(url); err != nil {
+			return err
+		}
+	}
+
+	config.Set("remote."+name+".url", url)
+	return config.Write()
+}
+
+// RemoveRemote forgets a remote together with its remote-tracking refs.
+func RemoveRemote(name string) error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	if !config.RemoveSection("remote", name) {
+		return fmt.Errorf("no such remote: '%s'", name)
+	}
+	if err := config.Write(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(repoPath(filepath.Join(constants.RefsDir, "remotes", name))); err != nil {
+		return err
+	}
+	return os.RemoveAll(repoPath(filepath.Join(constants.LogsDir, "refs", "remotes", name)))
+}
+
+// remoteGTDir returns the .gt directory of the repository at url.
+func remoteGTDir(url string) (string, error) {
+	gtDir := filepath.Join(url, constants.GTDir)
+	if _, err := os.Stat(repoPath(filepath.Join(gtDir, "objects"))); err != nil {
+		return "", fmt.Errorf("'%s' does not appear to be a GoTrack repository", url)
+	}
+	return gtDir, nil
+}
+
+// RefUpdate describes how one ref moved during a fetch or push.
+type RefUpdate struct {
+	Ref     string // Ref that was written
+	Source  string // Ref it was copied from
+	OldHash string // "" for a new ref
+	NewHash string // "" for a deleted ref
+}
+
+func (u RefUpdate) String() string {
+	from, to := shortRef(u.Source), shortRef(u.Ref)
+	switch {
+	case u.OldHash == "":
+		return fmt.Sprintf(" * [new]             %s -> %s", from, to)
+	case u.NewHash == "":
+		return fmt.Sprintf(" - [deleted]         %s", to)
+	default:
+		return fmt.Sprintf("   %s..%s  %s -> %s", u.OldHash[:7], u.NewHash[:7], from, to)
+	}
+}
+
+func shortRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			return name
+		}
+	}
+	return ref
+}
+
+// Fetch copies the objects of the remote's branches and tags that are
+// missing here, points refs/remotes/<name>/<branch> at the remote branches
+// and drops remote-tracking refs of branches the remote no longer has. Tags
+// are only created, never moved. A partial clone keeps leaving blobs out.
+func Fetch(name string) ([]RefUpdate, error) {
+	remote, err := GetRemote(name)
+	if err != nil {
+		return nil, err
+	}
+	return fetchRemote(remote, FetchOptions{BlobNone: remote.Promisor})
+}
+
+func fetchRemote(remote Remote, opts FetchOptions) ([]RefUpdate, error) {
+	name := remote.Name
+	t, err := openTransport(remote.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRefs, _, err := t.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	// Whatever the transport, ref names from elsewhere become paths here.
+	for ref := range remoteRefs {
+		if err := checkTransferRef(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	var wants []string
+	for ref, hash := range remoteRefs {
+		// A shallow fetch follows branches only; tags come along when
+		// they point into the history it brought.
+		if opts.Depth > 0 && !strings.HasPrefix(ref, "refs/heads/") {
+			continue
+		}
+		if !ObjectExists(hash) {
+			wants = append(wants, hash)
+		}
+	}
+	if len(wants) > 0 {
+		haves, err := localTips()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.fetchObjects(wants, haves, opts); err != nil {
+			return nil, err
+		}
+		if opts.Depth > 0 {
+			if err := updateShallow(wants); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var updates []RefUpdate
+	for _, source := range sortedKeys(remoteRefs) {
+		hash := remoteRefs[source]
+
+		ref := source
+		if branch, ok := strings.CutPrefix(source, "refs/heads/"); ok {
+			ref = "refs/remotes/" + name + "/" + branch
+		}
+
+		old, err := ReadRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		if old == hash || (old != "" && strings.HasPrefix(ref, "refs/tags/")) || !ObjectExists(hash) {
+			continue
+		}
+
+		if strings.HasPrefix(ref, "refs/tags/") {
+			err = WriteRef(ref, hash)
+		} else {
+			err = UpdateRef(ref, hash, "fetch: "+name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, RefUpdate{Ref: ref, Source: source, OldHash: old, NewHash: hash})
+	}
+
+	// Prune remote-tracking refs whose branch is gone.
+	tracked, err := ListRefs("refs/remotes/" + name)
+	if err != nil {
+		return nil, err
+	}
+	for _, branch := range tracked {
+		if _, ok := remoteRefs["refs/heads/"+branch]; ok {
+			continue
+		}
+		ref := "refs/remotes/" + name + "/" + branch
+		old, _ := ReadRef(ref)
+		if err := DeleteRef(ref); err != nil {
+			return nil, err
+		}
+		updates = append(updates, RefUpdate{Ref: ref, Source: "refs/heads/" + branch, OldHash: old})
+	}
+
+	return updates, nil
+}
+
+// localTips lists the commits local refs point at, which tell a remote what
+// history is already here.
+func localTips() ([]string, error) {
+	refs, err := ListRefs("refs")
+	if err != nil {
+		return nil, err
+	}
+
+	var tips []string
+	for _, ref := range refs {
+		hash, err := ReadRef("refs/" + ref)
+		if err != nil {
+			return nil, err
+		}
+		tips = append(tips, hash)
+	}
+	return tips, nil
+}
+
+// Push copies the objects of a local branch to the remote and moves the
+// remote branch to it. Unless force is set the remote branch must be an
+// ancestor of the local one. The branch checked out in the remote cannot
+// be pushed to since that would leave its working tree out of date.
+func Push(name string, branch string, force bool) (RefUpdate, error) {
+	remote, err := GetRemote(name)
+	if err != nil {
+		return RefUpdate{}, err
+	}
+	t, err := openTransport(remote.URL)
+	if err != nil {
+		return RefUpdate{}, err
+	}
+
+	ref := "refs/heads/" + branch
+	local, err := ReadRef(ref)
+	if err != nil {
+		return RefUpdate{}, err
+	}
+	if local == "" {
+		return RefUpdate{}, fmt.Errorf("src refspec %s does not match any branch", branch)
+	}
+
+	remoteRefs, _, err := t.listRefs()
+	if err != nil {
+		return RefUpdate{}, err
+	}
+
+	old := remoteRefs[ref]
+	update := RefUpdate{Ref: ref, Source: ref, OldHash: old, NewHash: local}
+	if old == local {
+		return update, nil
+	}
+
+	if old != "" && !force {
+		ancestors, err := Ancestors(local)
+		if err != nil {
+			return RefUpdate{}, err
+		}
+		if !ancestors[old] {
+			return RefUpdate{}, fmt.Errorf("rejected %s (non-fast-forward); fetch and integrate the remote changes first, or use --force", branch)
+		}
+	}
+
+	// Whatever the remote's refs point at that we also have need not be sent.
+	var haves []string
+	for _, hash := range remoteRefs {
+		if ObjectExists(hash) {
+			haves = append(haves, hash)
+		}
+	}
+
+	if err := t.push(update, haves); err != nil {
+		return RefUpdate{}, err
+	}
+
+	// Our view of the remote now matches what we pushed.
+	if err := UpdateRef("refs/remotes/"+name+"/"+branch, local, "update by push"); err != nil {
+		return RefUpdate{}, err
+	}
+
+	return update, nil
+}
+
+// SetUpstream records that branch tracks branch of the same name on remote.
+func SetUpstream(branch string, remote string) error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	config.Set("branch."+branch+".remote", remote)
+	config.Set("branch."+branch+".merge", "refs/heads/"+branch)
+	return config.Write()
+}
+
+// Clone creates dir, fetches everything from the repository at url (a path,
+// a bundle file or an http(s) URL) into it as remote "origin" and checks
+// out the branch the source has checked out. opts makes a shallow clone,
+// with the last opts.Depth commits of each branch, or a partial clone that
+// fetches blobs as they are needed. The process working directory is
+// restored before returning.
+func Clone(url string, dir string, opts FetchOptions) error {
+	if !isHTTPURL(url) {
+		var err error
+		if url, err = filepath.Abs(url); err != nil {
+			return err
+		}
+	}
+	t, err := openTransport(url)
+	if err != nil {
+		return err
+	}
+	_, remoteHead, err := t.listRefs()
+	if err != nil {
+		return err
+	}
+	if remoteHead != "" {
+		if err := checkTransferRef(remoteHead); err != nil {
+			return err
+		}
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("destination path '%s' already exists and is not an empty directory", dir)
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+
+	if err := cloneInto(url, remoteHead, opts); err != nil {
+		os.Chdir(wd)
+		// Do not leave a half-made repository behind.
+		os.RemoveAll(dir)
+		return err
+	}
+	return os.Chdir(wd)
+}
+
+// cloneInto sets up the repository in the current directory for Clone.
+func cloneInto(url string, remoteHead string, opts FetchOptions) error {
+
+	branch := constants.DefaultBranch
+	if name, ok := strings.CutPrefix(remoteHead, "refs/heads/"); ok {
+		branch = name
+	}
+
+	if err := os.MkdirAll(constants.ObjectsDir, 0755); err != nil {
+		return err
+	}
+	if err := writeHead("ref: refs/heads/" + branch); err != nil {
+		return err
+	}
+
+	if err := AddRemote(constants.DefaultRemote, url); err != nil {
+		return err
+	}
+	if opts.BlobNone {
+		if err := markPromisor(constants.DefaultRemote); err != nil {
+			return err
+		}
+	}
+	remote, err := GetRemote(constants.DefaultRemote)
+	if err != nil {
+		return err
+	}
+	if _, err := fetchRemote(remote, opts); err != nil {
+		return err
+	}
+
+	hash, err := ReadRef("refs/remotes/" + constants.DefaultRemote + "/" + branch)
+	if err != nil || hash == "" {
+		// An empty repository: nothing to check out.
+		return err
+	}
+
+	if err := UpdateRef("refs/heads/"+branch, hash, "clone: from "+url); err != nil {
+		return err
+	}
+	if err := SetUpstream(branch, constants.DefaultRemote); err != nil {
+		return err
+	}
+
+	files, err := FlattenCommit(hash)
+	if err != nil {
+		return err
+	}
+	// Get the files a partial clone checks out in one go rather than
+	// one by one.
+	if err := prefetchBlobs(remote, files); err != nil {
+		return err
+	}
+	if err := UpdateWorkingTree(map[string]IndexEntry{}, files); err != nil {
+		return err
+	}
+	return WriteIndex(files)
+}
+
+// copyObjects copies every object reachable from tips that dst does not
+// have yet and returns how many were copied. Objects are written after the
+// ones they reference, so an object already in dst comes with everything it
+// references and the walk can stop there, even after an interrupted copy.
+func copyObjects(src string, dst string, tips []string) (int, error) {
+	objects := make(map[string][]byte)
+	pending := append([]string(nil), tips...)
+
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, seen := objects[hash]; hash == "" || seen || objectExistsIn(dst, hash) {
+			continue
+		}
+
+		raw, err := readRawObject(src, hash)
+		if err != nil {
+			return 0, fmt.Errorf("missing object %s: %w", hash, err)
+		}
+		objType, data, err := parseObject(raw)
+		if err != nil {
+			return 0, err
+		}
+		objects[hash] = raw
+		pending = append(pending, objectReferences(objType, data)...)
+	}
+
+	return len(objects), writeObjectsInOrder(objects, dst)
+}
+
+// writeObjectsInOrder stores the objects, keyed by hash, in objectsDir so
+// that each one is written only after the objects it references among them.
+// An interrupted transfer then never leaves an object whose tree, parent or
+// blobs are missing. Trees with entry names that could escape the working
+// tree are refused before anything is written.
+func writeObjectsInOrder(objects map[string][]byte, objectsDir string) error {
+	for _, raw := range objects {
+		if objType, data, err := parseObject(raw); err == nil && objType == "tree" {
+			if err := checkTreeNames(data); err != nil {
+				return err
+			}
+		}
+	}
+
+	references := func(hash string) ([]string, error) {
+		objType, data, err := parseObject(objects[hash])
+		if err != nil {
+			return nil, err
+		}
+		return objectReferences(objType, data), nil
+	}
+
+	type frame struct {
+		hash string
+		refs []string
+	}
+	queued := make(map[string]bool)
+
+	for _, root := range sortedKeys(objects) {
+		if queued[root] {
+			continue
+		}
+		refs, err := references(root)
+		if err != nil {
+			return err
+		}
+		queued[root] = true
+		stack := []frame{{hash: root, refs: refs}}
+
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if len(top.refs) > 0 {
+				ref := top.refs[len(top.refs)-1]
+				top.refs = top.refs[:len(top.refs)-1]
+				if _, ok := objects[ref]; !ok || queued[ref] {
+					continue
+				}
+				refs, err := references(ref)
+				if err != nil {
+					return err
+				}
+				queued[ref] = true
+				stack = append(stack, frame{hash: ref, refs: refs})
+				continue
+			}
+
+			if err := writeObjectFile(top.hash, objects[top.hash], objectsDir); err != nil {
+				return err
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return nil
+}
+
+// objectReferences lists the hashes an object points at.
+func objectReferences(objType string, data []byte) []string {
+	switch objType {
+	case "commit":
+		commit := ParseCommit(string(data))
+		return []string{commit.TreeHash, commit.ParentHash}
+	case "tree":
+		var refs []string
+		for _, entry := range ParseTree(string(data), "").Entries {
+			if entry.Type != "commit" {
+				refs = append(refs, entry.Hash)
+			}
+		}
+		return refs
+	case "tag":
+		return []string{ParseTag(string(data)).Object}
+	}
+	return nil
+}
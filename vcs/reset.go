@@ -0,0 +1,112 @@
+package vcs
+
+import (
+	"fmt"
+)
+
+const (
+	ResetSoft  = "soft"  // Move the branch only
+	ResetMixed = "mixed" // Move the branch and reset the index
+	ResetHard  = "hard"  // Move the branch, reset the index and working tree
+)
+
+// ResetTo moves the current branch, or the detached HEAD, to rev and resets
+// the index and working tree according to mode.
+func ResetTo(rev string, mode string) (string, error) {
+	if mode != ResetSoft && mode != ResetMixed && mode != ResetHard {
+		return "", fmt.Errorf("unknown reset mode: %s", mode)
+	}
+
+	target, err := ResolveRevision(rev)
+	if err != nil {
+		return "", err
+	}
+
+	_, oldHead, err := ReadHead()
+	if err != nil {
+		return "", err
+	}
+
+	// Everything tracked before the reset, so --hard can remove what the
+	// target no longer has.
+	tracked, err := ReadIndex()
+	if err != nil {
+		return "", err
+	}
+	headFiles, err := FlattenCommit(oldHead)
+	if err != nil {
+		return "", err
+	}
+	for path, entry := range headFiles {
+		tracked[path] = entry
+	}
+
+	targetFiles, err := FlattenCommit(target)
+	if err != nil {
+		return "", err
+	}
+
+	if err := UpdateLatestCommit(target, "reset: moving to "+rev); err != nil {
+		return "", err
+	}
+
+	if mode == ResetSoft {
+		return target, nil
+	}
+
+	if err := WriteIndex(targetFiles); err != nil {
+		return "", err
+	}
+
+	if mode == ResetHard {
+		if err := UpdateWorkingTree(tracked, targetFiles); err != nil {
+			return "", err
+		}
+	}
+
+	return target, nil
+}
+
+// ResetPaths copies the entries for paths from rev into the index, unstaging
+// them, without moving HEAD or touching the working tree.
+func ResetPaths(rev string, paths []string) error {
+	targetFiles := make(map[string]IndexEntry)
+
+	_, headHash, err := ReadHead()
+	if err != nil {
+		return err
+	}
+
+	// Before the first commit there is nothing to reset to but an empty tree.
+	if rev != "HEAD" || headHash != "" {
+		target, err := ResolveRevision(rev)
+		if err != nil {
+			return err
+		}
+		if targetFiles, err = FlattenCommit(target); err != nil {
+			return err
+		}
+	}
+
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		spec := cleanPathspec(path)
+
+		for indexPath := range index {
+			if matchesPathspec(indexPath, spec) {
+				delete(index, indexPath)
+			}
+		}
+		for targetPath, entry := range targetFiles {
+			if matchesPathspec(targetPath, spec) {
+				index[targetPath] = entry
+			}
+		}
+	}
+
+	return WriteIndex(index)
+}
@@ -0,0 +1,125 @@
+package vcs
+
+import (
+	"fmt"
+)
+
+// RestorePaths brings back single files or directories. With staged set the
+// index entries are reset from source (HEAD by default); with worktree set
+// the files are rewritten from source, or from the index when source is "".
+// Files outside paths are never touched.
+func RestorePaths(source string, paths []string, staged bool, worktree bool) error {
+	if staged {
+		rev := source
+		if rev == "" {
+			rev = "HEAD"
+		}
+		if err := ResetPaths(rev, paths); err != nil {
+			return err
+		}
+	}
+
+	if !worktree {
+		return nil
+	}
+
+	var files map[string]IndexEntry
+	var err error
+	if source == "" {
+		files, err = restoreFromIndex(paths)
+	} else {
+		files, err = restoreFromCommit(source, paths)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, path := range sortedPaths(files) {
+		// Submodules are brought back by "submodule update".
+		if files[path].Mode == GitlinkMode {
+			continue
+		}
+		if err := writeWorkingFile(path, files[path].Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreFromIndex(paths []string) (map[string]IndexEntry, error) {
+	index, err := ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]IndexEntry)
+	for _, path := range paths {
+		spec := cleanPathspec(path)
+
+		matched := false
+		for indexPath, entry := range index {
+			if matchesPathspec(indexPath, spec) {
+				files[indexPath] = entry
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("pathspec '%s' did not match any file known to the index", path)
+		}
+	}
+
+	return files, nil
+}
+
+func restoreFromCommit(rev string, paths []string) (map[string]IndexEntry, error) {
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := ReadCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]IndexEntry)
+	for _, path := range paths {
+		spec := cleanPathspec(path)
+
+		if spec == "." {
+			all, err := FlattenTree(commit.TreeHash)
+			if err != nil {
+				return nil, err
+			}
+			for filePath, entry := range all {
+				files[filePath] = entry
+			}
+			continue
+		}
+
+		entry, found, err := FindTreeEntry(commit.TreeHash, spec)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("pathspec '%s' did not match any file in %s", path, rev)
+		}
+
+		if entry.Type == "blob" {
+			files[spec] = IndexEntry{Mode: entry.Mode, Hash: entry.Hash, Path: spec}
+			continue
+		}
+
+		err = WalkTree(entry.Hash, spec, func(filePath string, child TreeEntry) error {
+			if child.Type == "blob" {
+				files[filePath] = IndexEntry{Mode: child.Mode, Hash: child.Hash, Path: filePath}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,455 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sequencerState is what .gt/sequencer holds while a revert or cherry-pick is
+// in progress, so that it can be resumed after stopping on a conflict or
+// being interrupted.
+type sequencerState struct {
+	Operation string   // Command that started the sequence
+	Head      string   // HEAD before the sequence started, restored by --abort
+	Todo      []string // Remaining "<action> <hash>" steps, current one first
+	NoCommit  bool     // Leave the changes in the index instead of committing
+	Conflicts []string // Paths left with conflict markers by the current step
+	Message   string   // Commit message for the current step
+	Author    string   // Author for the current step's commit
+}
+
+func sequencerInProgress() bool {
+	_, err := os.Stat(repoPath(constants.SequencerDir))
+	return err == nil
+}
+
+func readSequencer() (sequencerState, error) {
+	state := sequencerState{}
+
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(repoPath(filepath.Join(constants.SequencerDir, name)))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	opts, err := read("opts")
+	if err != nil {
+		return state, err
+	}
+	for _, line := range strings.Split(opts, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "operation":
+			state.Operation = value
+		case "no-commit":
+			state.NoCommit = true
+		}
+	}
+
+	head, err := read("head")
+	if err != nil {
+		return state, err
+	}
+	state.Head = strings.TrimSpace(head)
+
+	todo, err := read("todo")
+	if err != nil {
+		return state, err
+	}
+	state.Todo = nonEmptyLines(todo)
+
+	conflicts, err := read("conflicts")
+	if err != nil {
+		return state, err
+	}
+	state.Conflicts = nonEmptyLines(conflicts)
+
+	if state.Message, err = read("message"); err != nil {
+		return state, err
+	}
+
+	author, err := read("author")
+	if err != nil {
+		return state, err
+	}
+	state.Author = strings.TrimSpace(author)
+
+	return state, nil
+}
+
+func writeSequencer(state sequencerState) error {
+	if err := os.MkdirAll(repoPath(constants.SequencerDir), 0755); err != nil {
+		return err
+	}
+
+	opts := "operation " + state.Operation + "\n"
+	if state.NoCommit {
+		opts += "no-commit\n"
+	}
+
+	files := map[string]string{
+		"opts":      opts,
+		"head":      state.Head + "\n",
+		"todo":      joinLines(state.Todo),
+		"conflicts": joinLines(state.Conflicts),
+		"message":   state.Message,
+		"author":    state.Author + "\n",
+	}
+	for name, content := range files {
+		if err := writeFileAtomic(filepath.Join(constants.SequencerDir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeSequencer() error {
+	return os.RemoveAll(repoPath(constants.SequencerDir))
+}
+
+// StartSequence applies each commit on top of HEAD, as a "revert" or a
+// "cherry-pick" step depending on operation.
+func StartSequence(operation string, revs []string, noCommit bool) error {
+	if sequencerInProgress() {
+		state, _ := readSequencer()
+		return fmt.Errorf("a %s is already in progress; use --continue or --abort", state.Operation)
+	}
+	if rebaseInProgress() {
+		return fmt.Errorf("a rebase is in progress; use gt rebase --continue or --abort")
+	}
+
+	if err := requireCleanTree(); err != nil {
+		return err
+	}
+
+	_, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	if head == "" {
+		return fmt.Errorf("cannot %s on top of an empty history", operation)
+	}
+
+	shallow, err := ReadShallow()
+	if err != nil {
+		return err
+	}
+
+	state := sequencerState{Operation: operation, Head: head, NoCommit: noCommit}
+	for _, rev := range revs {
+		hash, err := ResolveRevision(rev)
+		if err != nil {
+			return err
+		}
+		// Without its parent there is no change to apply or undo.
+		if shallow[hash] {
+			return fmt.Errorf("cannot %s %s: its parent was not fetched in this shallow clone", operation, hash[:7])
+		}
+		state.Todo = append(state.Todo, sequencerAction(operation)+" "+hash)
+	}
+
+	if err := writeSequencer(state); err != nil {
+		return err
+	}
+
+	return runSequence(state)
+}
+
+// ContinueSequence commits the resolved conflicts of the stopped step and
+// carries on with the remaining ones.
+func ContinueSequence(operation string) error {
+	state, err := loadSequencer(operation)
+	if err != nil {
+		return err
+	}
+
+	if len(state.Conflicts) > 0 {
+		if err := stageResolved(state.Conflicts); err != nil {
+			return err
+		}
+		if err := finishStep(state); err != nil {
+			return err
+		}
+		state.Todo = state.Todo[1:]
+		state.Conflicts = nil
+		state.Message = ""
+		if err := writeSequencer(state); err != nil {
+			return err
+		}
+	}
+
+	return runSequence(state)
+}
+
+// SkipSequence drops the step that stopped, resets the index and working
+// tree to HEAD and carries on with the remaining steps.
+func SkipSequence(operation string) error {
+	state, err := loadSequencer(operation)
+	if err != nil {
+		return err
+	}
+	if len(state.Todo) == 0 {
+		return fmt.Errorf("nothing to skip")
+	}
+
+	if err := discardStep(state.Conflicts, "HEAD"); err != nil {
+		return err
+	}
+
+	state.Todo = state.Todo[1:]
+	state.Conflicts = nil
+	state.Message = ""
+	if err := writeSequencer(state); err != nil {
+		return err
+	}
+
+	return runSequence(state)
+}
+
+// AbortSequence puts the branch, index and working tree back to where they
+// were before the sequence started.
+func AbortSequence(operation string) error {
+	state, err := loadSequencer(operation)
+	if err != nil {
+		return err
+	}
+
+	if err := discardStep(state.Conflicts, state.Head); err != nil {
+		return err
+	}
+
+	return removeSequencer()
+}
+
+// discardStep hard resets to rev, also removing conflicted files that rev
+// does not have since the reset does not consider them tracked.
+func discardStep(conflicts []string, rev string) error {
+	target, err := ResolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	targetFiles, err := FlattenCommit(target)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ResetTo(target, ResetHard); err != nil {
+		return err
+	}
+
+	for _, path := range conflicts {
+		if _, ok := targetFiles[path]; !ok {
+			os.Remove(path)
+			removeEmptyParents(path)
+		}
+	}
+
+	return nil
+}
+
+// sequencerAction is the todo verb an operation records for each commit.
+func sequencerAction(operation string) string {
+	if operation == "cherry-pick" {
+		return "pick"
+	}
+	return operation
+}
+
+func loadSequencer(operation string) (sequencerState, error) {
+	if !sequencerInProgress() {
+		return sequencerState{}, fmt.Errorf("no %s in progress", operation)
+	}
+
+	state, err := readSequencer()
+	if err != nil {
+		return state, err
+	}
+	if state.Operation != operation {
+		return state, fmt.Errorf("a %s is in progress, not a %s", state.Operation, operation)
+	}
+
+	return state, nil
+}
+
+// runSequence applies the remaining steps until one stops on a conflict.
+func runSequence(state sequencerState) error {
+	for len(state.Todo) > 0 {
+		action, hash, _ := strings.Cut(state.Todo[0], " ")
+
+		message, author, conflicts, err := applyStep(action, hash)
+		if err != nil {
+			return err
+		}
+
+		state.Message = message
+		state.Author = author
+		if len(conflicts) > 0 {
+			state.Conflicts = conflicts
+			if err := writeSequencer(state); err != nil {
+				return err
+			}
+			for _, path := range conflicts {
+				fmt.Printf("CONFLICT: Merge conflict in %s\n", path)
+			}
+			return fmt.Errorf("could not %s %s; resolve the conflicts and run 'gt %s --continue', or 'gt %s --abort'",
+				action, hash[:7], state.Operation, state.Operation)
+		}
+
+		if err := finishStep(state); err != nil {
+			return err
+		}
+
+		state.Todo = state.Todo[1:]
+		state.Message = ""
+		if err := writeSequencer(state); err != nil {
+			return err
+		}
+	}
+
+	return removeSequencer()
+}
+
+// applyStep merges the change made by one commit (or its inverse) into the
+// index and working tree and returns the message and author for the
+// resulting commit.
+func applyStep(action string, hash string) (string, string, []string, error) {
+	commit, err := ReadCommit(hash)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	parentFiles, err := FlattenCommit(commit.ParentHash)
+	if err != nil {
+		return "", "", nil, err
+	}
+	commitFiles, err := FlattenCommit(hash)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	var base, theirs map[string]IndexEntry
+	var message, author, label string
+
+	switch action {
+	case "revert":
+		// The inverse change: from the commit back to its parent.
+		base, theirs = commitFiles, parentFiles
+		message = fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", firstLine(commit.Message), hash)
+		author = GetIdentity()
+		label = "parent of " + hash[:7] + " (" + firstLine(commit.Message) + ")"
+	case "pick":
+		// The commit's own change, keeping who wrote it.
+		base, theirs = parentFiles, commitFiles
+		message = commit.Message
+		author = commit.Author
+		if author == "" {
+			author = GetIdentity()
+		}
+		label = hash[:7] + " (" + firstLine(commit.Message) + ")"
+	default:
+		return "", "", nil, fmt.Errorf("unknown sequencer action: %s", action)
+	}
+
+	ours, err := ReadIndex()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	result, err := MergeTrees(base, ours, theirs, "HEAD", label)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if err := applyMergeResult(ours, result); err != nil {
+		return "", "", nil, err
+	}
+
+	return message, author, result.Conflicts, nil
+}
+
+// finishStep commits the index for the current step unless --no-commit was
+// given or the step turned out to change nothing.
+func finishStep(state sequencerState) error {
+	if state.NoCommit {
+		return nil
+	}
+
+	_, head, err := ReadHead()
+	if err != nil {
+		return err
+	}
+	headFiles, err := FlattenCommit(head)
+	if err != nil {
+		return err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+	if BuildTreeFromIndex(index).Hash == BuildTreeFromIndex(headFiles).Hash {
+		fmt.Println("Nothing to commit, the change is already applied.")
+		return nil
+	}
+
+	commit, err := CommitIndex(state.Message, state.Author, state.Operation)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[%s] %s\n", commit.Hash[:7], firstLine(commit.Message))
+	return nil
+}
+
+// stageResolved adds the conflicted paths to the index once no conflict
+// markers are left in them. Deleted paths are removed from the index.
+func stageResolved(paths []string) error {
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			delete(index, path)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if hasConflictMarkers(content) {
+			return fmt.Errorf("'%s' still contains conflict markers", path)
+		}
+
+		hash, err := WriteBlobContent(content, constants.ObjectsDir)
+		if err != nil {
+			return err
+		}
+		index[path] = IndexEntry{Mode: "100644", Hash: hash, Path: path}
+	}
+
+	return WriteIndex(index)
+}
+
+func nonEmptyLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
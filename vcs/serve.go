@@ -0,0 +1,184 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server exposes the repository in a directory over HTTP:
+//
+//	GET  /info/refs     ref advertisement
+//	POST /upload-pack   "want <hash>" and "have <hash>" lines, optionally
+//	                    "depth <n>" and "filter blob:none", answered with a pack
+//	POST /receive-pack  "<old> <new> <ref>", a blank line and a pack, answered
+//	                    with "ok <ref>" or "ng <ref> <reason>"
+type Server struct {
+	gtDir string
+	mu    sync.Mutex // Serializes ref updates
+}
+
+// NewServer serves the repository whose working directory is dir.
+func NewServer(dir string) (*Server, error) {
+	gtDir, err := remoteGTDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{gtDir: gtDir}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/info/refs" && r.Method == http.MethodGet:
+		s.handleRefs(w)
+	case r.URL.Path == "/upload-pack" && r.Method == http.MethodPost:
+		s.handleUploadPack(w, r)
+	case r.URL.Path == "/receive-pack" && r.Method == http.MethodPost:
+		s.handleReceivePack(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) objectsDir() string {
+	return filepath.Join(s.gtDir, "objects")
+}
+
+func (s *Server) handleRefs(w http.ResponseWriter) {
+	s.mu.Lock()
+	refs, head, err := advertisedRefs(s.gtDir)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	writeRefAdvertisement(w, refs, head)
+}
+
+func (s *Server) handleUploadPack(w http.ResponseWriter, r *http.Request) {
+	var wants, haves []string
+	var opts FetchOptions
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		verb, hash, _ := strings.Cut(scanner.Text(), " ")
+		// Hashes become paths below the objects directory.
+		if (verb == "want" || verb == "have") && !isObjectHash(hash) {
+			http.Error(w, fmt.Sprintf("bad object hash %q", hash), http.StatusBadRequest)
+			return
+		}
+		switch verb {
+		case "want":
+			if !objectExistsIn(s.objectsDir(), hash) {
+				http.Error(w, "not our ref "+hash, http.StatusBadRequest)
+				return
+			}
+			wants = append(wants, hash)
+		case "have":
+			haves = append(haves, hash)
+		case "depth":
+			depth, err := strconv.Atoi(hash)
+			if err != nil || depth < 1 {
+				http.Error(w, "bad depth "+hash, http.StatusBadRequest)
+				return
+			}
+			opts.Depth = depth
+		case "filter":
+			if hash != "blob:none" {
+				http.Error(w, "unsupported filter "+hash, http.StatusBadRequest)
+				return
+			}
+			opts.BlobNone = true
+		case "done":
+		default:
+			http.Error(w, fmt.Sprintf("unexpected line %q", scanner.Text()), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	objects, err := packObjects(s.objectsDir(), wants, haves, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The pack is built before anything is sent so a failure can still be
+	// reported with an error status.
+	var pack bytes.Buffer
+	if err := writePack(&pack, s.objectsDir(), objects); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(pack.Bytes())
+}
+
+func (s *Server) handleReceivePack(w http.ResponseWriter, r *http.Request) {
+	body := bufio.NewReader(r.Body)
+
+	var updates []RefUpdate
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			http.Error(w, "truncated push request", http.StatusBadRequest)
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !isObjectHash(fields[0]) || !isObjectHash(fields[1]) {
+			http.Error(w, fmt.Sprintf("bad update line %q", line), http.StatusBadRequest)
+			return
+		}
+		update := RefUpdate{Ref: fields[2], Source: fields[2], OldHash: fields[0], NewHash: fields[1]}
+		if update.OldHash == ZeroHash {
+			update.OldHash = ""
+		}
+		updates = append(updates, update)
+	}
+
+	if _, err := readPack(body, s.objectsDir()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	for _, update := range updates {
+		if err := receiveUpdate(s.gtDir, update); err != nil {
+			fmt.Fprintf(w, "ng %s %s\n", update.Ref, err)
+			continue
+		}
+		fmt.Fprintf(w, "ok %s\n", update.Ref)
+	}
+}
+
+// Serve serves the repository in the current directory on addr until the
+// server fails.
+func Serve(addr string) error {
+	server, err := NewServer(".")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Serving %s on http://%s\n", constants.GTDir, addr)
+	return http.ListenAndServe(addr, server)
+}
@@ -0,0 +1,94 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A shallow clone only has the most recent commits of each branch.
+// .gt/shallow lists the oldest commits it has, one hash per line; their
+// parents were never fetched and history walks stop at them.
+
+// ReadShallow returns the commits at the shallow boundary, or an empty set
+// for a complete repository.
+func ReadShallow() (map[string]bool, error) {
+	return readShallowIn(constants.GTDir)
+}
+
+func readShallowIn(gtDir string) (map[string]bool, error) {
+	shallow := make(map[string]bool)
+
+	data, err := os.ReadFile(repoPath(filepath.Join(gtDir, "shallow")))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return shallow, nil
+		}
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			shallow[line] = true
+		}
+	}
+	return shallow, nil
+}
+
+func writeShallow(shallow map[string]bool) error {
+	if len(shallow) == 0 {
+		if err := os.Remove(repoPath(constants.ShallowFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	hashes := sortedKeys(shallow)
+	return writeFileAtomic(constants.ShallowFile, []byte(strings.Join(hashes, "\n")+"\n"), 0644)
+}
+
+// updateShallow walks back from the fetched tips and adds every commit
+// whose parent did not come with the fetch to .gt/shallow.
+func updateShallow(tips []string) error {
+	shallow, err := ReadShallow()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, hash := range tips {
+		for hash != "" && !seen[hash] && !shallow[hash] {
+			seen[hash] = true
+
+			objType, data, err := readStoredObject(hash)
+			if err != nil {
+				return err
+			}
+			if objType == "tag" {
+				hash = ParseTag(string(data)).Object
+				continue
+			}
+			if objType != "commit" {
+				break
+			}
+
+			parent := ParseCommit(string(data)).ParentHash
+			if parent != "" && !ObjectExists(parent) {
+				shallow[hash] = true
+				break
+			}
+			hash = parent
+		}
+	}
+
+	return writeShallow(shallow)
+}
+
+// parentOf returns the parent of commit, or "" at the shallow boundary.
+func parentOf(commit Commit, shallow map[string]bool) string {
+	if shallow[commit.Hash] {
+		return ""
+	}
+	return commit.ParentHash
+}
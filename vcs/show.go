@@ -0,0 +1,141 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Show writes a readable form of the object rev names to out: a commit with
+// its diff against the parent, a tree as a listing, a blob's content, or a
+// tag followed by the object it points at.
+func Show(out io.Writer, rev string) error {
+	hash, err := ResolveObject(rev)
+	if err != nil {
+		return err
+	}
+	objType, data, err := ReadObjectType(hash)
+	if err != nil {
+		return err
+	}
+
+	switch objType {
+	case "commit":
+		commit := ParseCommit(string(data))
+		commit.Hash = hash
+		return showCommit(out, commit)
+
+	case "tree":
+		fmt.Fprintf(out, "tree %s\n\n", rev)
+		for _, entry := range ParseTree(string(data), hash).Entries {
+			if entry.Type == "tree" {
+				fmt.Fprintln(out, entry.Name+"/")
+			} else {
+				fmt.Fprintln(out, entry.Name)
+			}
+		}
+		return nil
+
+	case "tag":
+		tag := ParseTag(string(data))
+		fmt.Fprintf(out, "tag %s\nTagger: %s\nDate:   %s\n\n%s\n\n", tag.Name, tag.Tagger, formatDate(tag.TimeStamp), tag.Message)
+		return Show(out, tag.Object)
+	}
+
+	_, err = out.Write(data)
+	return err
+}
+
+func showCommit(out io.Writer, commit Commit) error {
+	fmt.Fprintf(out, "commit %s\n", commit.Hash)
+	if commit.Author != "" {
+		fmt.Fprintf(out, "Author: %s\n", commit.Author)
+	}
+	fmt.Fprintf(out, "Date:   %s\n\n", formatDate(commit.TimeStamp))
+	for _, line := range strings.Split(commit.Message, "\n") {
+		fmt.Fprintf(out, "    %s\n", line)
+	}
+	fmt.Fprintln(out)
+
+	// A shallow boundary commit is shown as if it added everything.
+	shallow, err := ReadShallow()
+	if err != nil {
+		return err
+	}
+	parentFiles, err := FlattenCommit(parentOf(commit, shallow))
+	if err != nil {
+		return err
+	}
+	files, err := FlattenTree(commit.TreeHash)
+	if err != nil {
+		return err
+	}
+
+	return writeTreeDiff(out, parentFiles, files)
+}
+
+// writeTreeDiff writes a unified diff of every file that differs between
+// from and to.
+func writeTreeDiff(out io.Writer, from map[string]IndexEntry, to map[string]IndexEntry) error {
+	paths := make(map[string]IndexEntry)
+	for _, side := range []map[string]IndexEntry{from, to} {
+		for path, entry := range side {
+			paths[path] = entry
+		}
+	}
+
+	for _, path := range sortedPaths(paths) {
+		oldEntry, inOld := from[path]
+		newEntry, inNew := to[path]
+		if inOld && inNew && oldEntry.Hash == newEntry.Hash {
+			continue
+		}
+
+		oldName, newName := "a/"+path, "b/"+path
+		fmt.Fprintf(out, "diff --gt %s %s\n", oldName, newName)
+		switch {
+		case !inOld:
+			fmt.Fprintf(out, "new file mode %s\n", newEntry.Mode)
+			oldName = "/dev/null"
+		case !inNew:
+			fmt.Fprintf(out, "deleted file mode %s\n", oldEntry.Mode)
+			newName = "/dev/null"
+		}
+
+		oldContent, err := diffContent(oldEntry, inOld)
+		if err != nil {
+			return err
+		}
+		newContent, err := diffContent(newEntry, inNew)
+		if err != nil {
+			return err
+		}
+
+		if bytes.IndexByte(oldContent, 0) != -1 || bytes.IndexByte(newContent, 0) != -1 {
+			fmt.Fprintf(out, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+		fmt.Fprint(out, UnifiedDiff(SplitLines(oldContent), SplitLines(newContent), 3))
+	}
+
+	return nil
+}
+
+// diffContent returns what a diff shows for an entry: the blob, or a line
+// naming the commit of a submodule.
+func diffContent(entry IndexEntry, exists bool) ([]byte, error) {
+	if !exists {
+		return nil, nil
+	}
+	if entry.Mode == GitlinkMode {
+		return []byte("Subproject commit " + entry.Hash + "\n"), nil
+	}
+	return ReadObject(entry.Hash)
+}
+
+func formatDate(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format("Mon Jan 2 15:04:05 2006 -0700")
+}
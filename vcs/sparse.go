@@ -0,0 +1,232 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"strings"
+)
+
+// SparseCheckout limits the working tree to part of the repository. In cone
+// mode the patterns are directories: files at the top level, directly inside
+// a parent of a listed directory, or anywhere below one are checked out. In
+// full mode they are gitignore style patterns where the last match wins and
+// a leading "!" excludes.
+//
+// A nil *SparseCheckout means sparse checkout is disabled and includes
+// everything.
+type SparseCheckout struct {
+	Cone     bool
+	Patterns []string
+}
+
+// ReadSparseCheckout loads .gt/sparse-checkout, returning nil when sparse
+// checkout is not enabled.
+func ReadSparseCheckout() (*SparseCheckout, error) {
+	data, err := os.ReadFile(repoPath(constants.SparseFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	sparse := &SparseCheckout{Cone: config.Get("sparse.cone") != "false"}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sparse.Patterns = append(sparse.Patterns, line)
+	}
+
+	return sparse, nil
+}
+
+// WriteSparseCheckout enables sparse checkout with the given patterns.
+func WriteSparseCheckout(sparse *SparseCheckout) error {
+	patterns := sparse.Patterns
+	if sparse.Cone {
+		patterns = nil
+		for _, dir := range sparse.Patterns {
+			dir = strings.Trim(cleanPathspec(dir), "/")
+			if dir == "." || dir == "" || strings.HasPrefix(dir, "..") {
+				return fmt.Errorf("invalid cone directory: %s", dir)
+			}
+			if strings.ContainsAny(dir, "*?[!") {
+				return fmt.Errorf("cone mode takes directories, not patterns: %s", dir)
+			}
+			patterns = append(patterns, dir)
+		}
+	}
+
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+	config.Set("sparse.cone", fmt.Sprint(sparse.Cone))
+	if err := config.Write(); err != nil {
+		return err
+	}
+
+	var data []byte
+	for _, pattern := range patterns {
+		data = append(data, []byte(pattern+"\n")...)
+	}
+	return writeFileAtomic(constants.SparseFile, data, 0644)
+}
+
+// Includes reports whether the file at path belongs in the working tree.
+func (s *SparseCheckout) Includes(path string) bool {
+	if s == nil {
+		return true
+	}
+	if s.Cone {
+		return s.coneIncludes(path)
+	}
+
+	included := false
+	for _, pattern := range s.Patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		if matchesSparsePattern(strings.TrimPrefix(pattern, "!"), path) {
+			included = !exclude
+		}
+	}
+	return included
+}
+
+func (s *SparseCheckout) coneIncludes(path string) bool {
+	dir := pathpkg.Dir(path)
+	if dir == "." {
+		return true
+	}
+
+	for _, cone := range s.Patterns {
+		// Below a cone directory, or directly inside one of its parents.
+		if strings.HasPrefix(path, cone+"/") || cone == dir || strings.HasPrefix(cone, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSparsePattern matches pattern against path and each directory
+// above it, so a matching directory takes its whole subtree with it.
+// Patterns containing a "/" are anchored at the top of the repository,
+// others match a single name at any depth, and a trailing "/" only matches
+// directories.
+func matchesSparsePattern(pattern string, path string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	names := strings.Split(path, "/")
+	for i := range names {
+		if dirOnly && i == len(names)-1 {
+			break
+		}
+
+		subject := names[i]
+		if anchored {
+			subject = strings.Join(names[:i+1], "/")
+		}
+		if ok, _ := pathpkg.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SparseResult reports how the working tree changed when the sparse
+// patterns were applied.
+type SparseResult struct {
+	Added   []string
+	Removed []string
+	Kept    []string // Outside the patterns but locally modified
+}
+
+// SetSparseCheckout replaces the sparse patterns and updates the working
+// tree to match them.
+func SetSparseCheckout(patterns []string, cone bool) (SparseResult, error) {
+	if err := WriteSparseCheckout(&SparseCheckout{Cone: cone, Patterns: patterns}); err != nil {
+		return SparseResult{}, err
+	}
+	return ReapplySparseCheckout()
+}
+
+// AddSparseCheckout adds patterns to the current sparse checkout.
+func AddSparseCheckout(patterns []string) (SparseResult, error) {
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return SparseResult{}, err
+	}
+	if sparse == nil {
+		return SparseResult{}, fmt.Errorf("sparse checkout is not enabled")
+	}
+
+	sparse.Patterns = append(sparse.Patterns, patterns...)
+	if err := WriteSparseCheckout(sparse); err != nil {
+		return SparseResult{}, err
+	}
+	return ReapplySparseCheckout()
+}
+
+// DisableSparseCheckout removes the patterns and checks out every file.
+func DisableSparseCheckout() (SparseResult, error) {
+	if err := os.Remove(repoPath(constants.SparseFile)); err != nil && !os.IsNotExist(err) {
+		return SparseResult{}, err
+	}
+	return ReapplySparseCheckout()
+}
+
+// ReapplySparseCheckout writes the indexed files the patterns include that
+// are missing from the working tree and removes the ones they exclude.
+// Excluded files with local changes are left in place.
+func ReapplySparseCheckout() (SparseResult, error) {
+	var result SparseResult
+
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return result, err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return result, err
+	}
+
+	for _, path := range sortedPaths(index) {
+		entry := index[path]
+		content, err := os.ReadFile(path)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+
+		switch included := sparse.Includes(path); {
+		case included && !exists:
+			if err := writeWorkingFile(path, entry.Hash); err != nil {
+				return result, err
+			}
+			result.Added = append(result.Added, path)
+
+		case !included && exists && HashContent(content) == entry.Hash:
+			if err := os.Remove(path); err != nil {
+				return result, err
+			}
+			removeEmptyParents(path)
+			result.Removed = append(result.Removed, path)
+
+		case !included && exists:
+			result.Kept = append(result.Kept, path)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package vcs
+
+import (
+	"sort"
+)
+
+// StatusEntry is one changed path. Kind is 'A', 'M' or 'D'.
+type StatusEntry struct {
+	Kind byte
+	Path string
+}
+
+// Status compares HEAD, the index and the working tree.
+type Status struct {
+	Staged    []StatusEntry // HEAD to index
+	Unstaged  []StatusEntry // Index to working tree
+	Untracked []string
+	Sparse    bool
+}
+
+// GetStatus collects the differences between HEAD, the index and the working
+// tree. Indexed files that the sparse checkout patterns leave out are not
+// expected in the working tree and are not reported as deleted.
+func GetStatus() (Status, error) {
+	var status Status
+
+	sparse, err := ReadSparseCheckout()
+	if err != nil {
+		return status, err
+	}
+	status.Sparse = sparse != nil
+
+	head, err := GetLatestCommitHash()
+	if err != nil {
+		return status, err
+	}
+	headFiles, err := FlattenCommit(head)
+	if err != nil {
+		return status, err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return status, err
+	}
+	files, err := ScanWorkingTree(".")
+	if err != nil {
+		return status, err
+	}
+
+	status.Staged = compareEntries(headFiles, index, nil, true)
+	status.Unstaged = compareEntries(index, files, sparse, false)
+
+	for path := range files {
+		if _, ok := index[path]; !ok {
+			status.Untracked = append(status.Untracked, path)
+		}
+	}
+	sort.Strings(status.Untracked)
+
+	return status, nil
+}
+
+// compareEntries lists how to differs from from. Paths only in to are
+// reported as added when withAdded is set; for the working tree they are
+// untracked instead. Paths the sparse patterns exclude are skipped.
+func compareEntries(from map[string]IndexEntry, to map[string]IndexEntry, sparse *SparseCheckout, withAdded bool) []StatusEntry {
+	var changes []StatusEntry
+
+	for path, entry := range from {
+		if !sparse.Includes(path) {
+			continue
+		}
+		if other, ok := to[path]; !ok {
+			changes = append(changes, StatusEntry{Kind: 'D', Path: path})
+		} else if other.Hash != entry.Hash {
+			changes = append(changes, StatusEntry{Kind: 'M', Path: path})
+		}
+	}
+
+	if withAdded {
+		for path := range to {
+			if _, ok := from[path]; !ok {
+				changes = append(changes, StatusEntry{Kind: 'A', Path: path})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
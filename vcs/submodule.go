@@ -0,0 +1,188 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A submodule is another repository cloned inside the working tree. Trees
+// record it as a gitlink, an entry of mode 160000 naming the commit the
+// submodule has checked out, and .gtmodules at the top of the working tree
+// says where to clone it from:
+//
+//	[submodule "lib/json"]
+//		path = lib/json
+//		url = https://example.com/json
+
+// GitlinkMode is the tree and index mode of a submodule.
+const GitlinkMode = "160000"
+
+// Submodule is one entry of .gtmodules.
+type Submodule struct {
+	Path     string
+	URL      string
+	Recorded string // Commit staged in the index, "" when not staged
+	Current  string // Commit checked out in the submodule, "" when not cloned
+}
+
+// isNestedRepo reports whether dir holds a repository of its own.
+func isNestedRepo(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, constants.GTDir))
+	return err == nil
+}
+
+// nestedHead returns the commit the repository in dir has checked out, or
+// "" when it has none.
+func nestedHead(dir string) string {
+	_, hash, err := readHeadIn(filepath.Join(dir, constants.GTDir))
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// ReadSubmodules lists the submodules of .gtmodules sorted by path.
+func ReadSubmodules() ([]Submodule, error) {
+	modules, err := readConfigFile(constants.ModulesFile)
+	if err != nil {
+		return nil, err
+	}
+	index, err := ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var submodules []Submodule
+	for _, name := range modules.Subsections("submodule") {
+		submodule := Submodule{
+			Path: modules.Get("submodule." + name + ".path"),
+			URL:  modules.Get("submodule." + name + ".url"),
+		}
+		if submodule.Path == "" {
+			submodule.Path = name
+		}
+		if entry, ok := index[submodule.Path]; ok && entry.Mode == GitlinkMode {
+			submodule.Recorded = entry.Hash
+		}
+		if isNestedRepo(submodule.Path) {
+			submodule.Current = nestedHead(submodule.Path)
+		}
+		submodules = append(submodules, submodule)
+	}
+
+	sort.Slice(submodules, func(i, j int) bool { return submodules[i].Path < submodules[j].Path })
+	return submodules, nil
+}
+
+// AddSubmodule clones url into path, records it in .gtmodules and stages
+// both the manifest and the gitlink.
+func AddSubmodule(url string, path string) error {
+	path = cleanPathspec(path)
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") || filepath.IsAbs(path) {
+		return fmt.Errorf("'%s' is outside the working tree", path)
+	}
+
+	index, err := ReadIndex()
+	if err != nil {
+		return err
+	}
+	for indexPath := range index {
+		if matchesPathspec(indexPath, path) {
+			return fmt.Errorf("'%s' already exists in the index", path)
+		}
+	}
+
+	if err := Clone(url, path, FetchOptions{}); err != nil {
+		return err
+	}
+	if nestedHead(path) == "" {
+		return fmt.Errorf("'%s' has no commit to record", url)
+	}
+
+	modules, err := readConfigFile(constants.ModulesFile)
+	if err != nil {
+		return err
+	}
+	modules.Set("submodule."+path+".path", path)
+	modules.Set("submodule."+path+".url", url)
+	if err := modules.writeFile(constants.ModulesFile); err != nil {
+		return err
+	}
+
+	return AddToIndex([]string{constants.ModulesFile, path})
+}
+
+// UpdateSubmodules clones the submodules that are missing and detaches each
+// at the commit the index records for it. It returns the submodules it
+// checked out.
+func UpdateSubmodules() ([]Submodule, error) {
+	submodules, err := ReadSubmodules()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []Submodule
+	for _, submodule := range submodules {
+		if submodule.Recorded == "" || submodule.Current == submodule.Recorded {
+			continue
+		}
+
+		if !isNestedRepo(submodule.Path) {
+			if submodule.URL == "" {
+				return updated, fmt.Errorf("no url for submodule '%s' in %s", submodule.Path, constants.ModulesFile)
+			}
+			if err := Clone(submodule.URL, submodule.Path, FetchOptions{}); err != nil {
+				return updated, fmt.Errorf("cloning '%s' failed: %w", submodule.Path, err)
+			}
+		}
+
+		if err := checkoutSubmodule(submodule.Path, submodule.Recorded); err != nil {
+			return updated, fmt.Errorf("submodule '%s': %w", submodule.Path, err)
+		}
+		submodule.Current = submodule.Recorded
+		updated = append(updated, submodule)
+	}
+
+	return updated, nil
+}
+
+// checkoutSubmodule detaches the HEAD of the submodule at path at hash,
+// fetching from its origin when the commit is not there yet. Local changes
+// in the submodule stop the update.
+func checkoutSubmodule(path string, hash string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(path); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	if !ObjectExists(hash) {
+		if _, err := Fetch(constants.DefaultRemote); err != nil {
+			return err
+		}
+		if !ObjectExists(hash) {
+			return fmt.Errorf("commit %s is not on the remote", hash)
+		}
+	}
+
+	status, err := GetStatus()
+	if err != nil {
+		return err
+	}
+	if len(status.Staged) > 0 || len(status.Unstaged) > 0 {
+		return fmt.Errorf("local changes would be overwritten")
+	}
+
+	from, err := headName()
+	if err != nil {
+		return err
+	}
+	return checkoutCommit(hash, fmt.Sprintf("checkout: moving from %s to %s", from, hash))
+}
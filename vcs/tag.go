@@ -0,0 +1,127 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag represents an annotated tag object
+type Tag struct {
+	Object    string // Hash of the tagged object
+	Type      string // Type of the tagged object
+	Name      string
+	Tagger    string
+	TimeStamp int64
+	Message   string
+	Hash      string // Tag object hash
+}
+
+func WriteTag(objectHash, objectType, name, message string, objectsDir string) (Tag, error) {
+	tag := Tag{
+		Object:    objectHash,
+		Type:      objectType,
+		Name:      name,
+		Tagger:    GetIdentity(),
+		TimeStamp: time.Now().Unix(),
+		Message:   message,
+	}
+
+	return writeTagObject(tag, objectsDir)
+}
+
+// writeTagObject stores tag as is, keeping its tagger and timestamp, and
+// returns it with Hash set.
+func writeTagObject(tag Tag, objectsDir string) (Tag, error) {
+	var tagData []byte
+	tagData = append(tagData, []byte(fmt.Sprintf("object %s\n", tag.Object))...)
+	tagData = append(tagData, []byte(fmt.Sprintf("type %s\n", tag.Type))...)
+	tagData = append(tagData, []byte(fmt.Sprintf("tag %s\n", tag.Name))...)
+	tagData = append(tagData, []byte(fmt.Sprintf("tagger %s\n", tag.Tagger))...)
+	tagData = append(tagData, []byte(fmt.Sprintf("timestamp %d\n", tag.TimeStamp))...)
+	// The message goes last so that it may span several lines.
+	tagData = append(tagData, []byte(fmt.Sprintf("message %s\n", tag.Message))...)
+
+	tagContent := append([]byte(fmt.Sprintf("tag %d\000", len(tagData))), tagData...)
+	tag.Hash = HashContent(tagContent)
+
+	if err := writeObjectFile(tag.Hash, tagContent, objectsDir); err != nil {
+		return Tag{}, err
+	}
+
+	return tag, nil
+}
+
+func ParseTag(data string) Tag {
+	tag := Tag{}
+
+	for len(data) > 0 {
+		line, rest, _ := strings.Cut(data, "\n")
+		key, value, _ := strings.Cut(line, " ")
+
+		switch key {
+		case "object":
+			tag.Object = value
+		case "type":
+			tag.Type = value
+		case "tag":
+			tag.Name = value
+		case "tagger":
+			tag.Tagger = value
+		case "timestamp":
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				tag.TimeStamp = timestamp
+			}
+		case "message":
+			tag.Message = strings.TrimSuffix(strings.TrimPrefix(data, "message "), "\n")
+			return tag
+		}
+
+		data = rest
+	}
+
+	return tag
+}
+
+// CreateTag points refs/tags/<name> at rev. With annotate set, a tag object
+// carrying the message is written and the ref points at that object instead.
+func CreateTag(name, rev string, annotate bool, message string, force bool) (string, error) {
+	if err := CheckRefName(name); err != nil {
+		return "", err
+	}
+
+	ref := "refs/tags/" + name
+	existing, err := ReadRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" && !force {
+		return "", fmt.Errorf("tag '%s' already exists", name)
+	}
+
+	target, err := ResolveObject(rev)
+	if err != nil {
+		return "", err
+	}
+
+	if annotate {
+		objType, _, err := ReadObjectType(target)
+		if err != nil {
+			return "", err
+		}
+		tag, err := WriteTag(target, objType, name, message, constants.ObjectsDir)
+		if err != nil {
+			return "", err
+		}
+		target = tag.Hash
+	}
+
+	if err := WriteRef(ref, target); err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
@@ -0,0 +1,297 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// transport is how fetch, push and clone talk to a remote repository,
+// either directly on disk, over HTTP to gt serve, or through a bundle file.
+type transport interface {
+	// listRefs returns the remote's branches and tags by full ref name,
+	// and the branch its HEAD points at.
+	listRefs() (map[string]string, string, error)
+	// fetchObjects copies into the local store what is reachable from
+	// wants and not from haves, limited by opts.
+	fetchObjects(wants []string, haves []string, opts FetchOptions) error
+	// push sends what update.NewHash needs and moves update.Ref from
+	// update.OldHash to update.NewHash on the remote.
+	push(update RefUpdate, haves []string) error
+}
+
+// FetchOptions limit how much a fetch copies.
+type FetchOptions struct {
+	Depth    int  // Commits of history to fetch per ref, 0 for all of it
+	BlobNone bool // Leave out file contents, they are fetched when read
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func openTransport(url string) (transport, error) {
+	if isHTTPURL(url) {
+		return &httpTransport{url: strings.TrimSuffix(url, "/"), client: http.DefaultClient}, nil
+	}
+	if isBundle(url) {
+		return &bundleTransport{path: url}, nil
+	}
+
+	gtDir, err := remoteGTDir(url)
+	if err != nil {
+		return nil, err
+	}
+	return &localTransport{gtDir: gtDir}, nil
+}
+
+// localTransport reads and writes another repository on the same machine.
+type localTransport struct {
+	gtDir string
+}
+
+func (t *localTransport) listRefs() (map[string]string, string, error) {
+	return advertisedRefs(t.gtDir)
+}
+
+func (t *localTransport) fetchObjects(wants []string, haves []string, opts FetchOptions) error {
+	src := filepath.Join(t.gtDir, "objects")
+	if opts == (FetchOptions{}) {
+		_, err := copyObjects(src, constants.ObjectsDir, wants)
+		return err
+	}
+
+	hashes, err := packObjects(src, wants, haves, opts)
+	if err != nil {
+		return err
+	}
+	objects := make(map[string][]byte)
+	for _, hash := range hashes {
+		if objectExistsIn(constants.ObjectsDir, hash) {
+			continue
+		}
+		raw, err := readRawObject(src, hash)
+		if err != nil {
+			return err
+		}
+		objects[hash] = raw
+	}
+	return writeObjectsInOrder(objects, constants.ObjectsDir)
+}
+
+func (t *localTransport) push(update RefUpdate, haves []string) error {
+	if _, err := copyObjects(constants.ObjectsDir, filepath.Join(t.gtDir, "objects"), []string{update.NewHash}); err != nil {
+		return err
+	}
+	return receiveUpdate(t.gtDir, update)
+}
+
+// advertisedRefs lists the branches and tags of the repository in gtDir.
+func advertisedRefs(gtDir string) (map[string]string, string, error) {
+	refs := make(map[string]string)
+	for _, prefix := range []string{"refs/heads", "refs/tags"} {
+		names, err := listRefsIn(gtDir, prefix)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, name := range names {
+			hash, err := readRefIn(gtDir, prefix+"/"+name)
+			if err != nil {
+				return nil, "", err
+			}
+			refs[prefix+"/"+name] = hash
+		}
+	}
+
+	head, _, err := readHeadIn(gtDir)
+	if err != nil {
+		return nil, "", err
+	}
+	return refs, head, nil
+}
+
+// receiveUpdate moves a ref of the repository in gtDir for a push. The ref
+// must still be at update.OldHash, and a branch checked out in any of its
+// worktrees cannot be updated since that would leave the working tree out
+// of date.
+func receiveUpdate(gtDir string, update RefUpdate) error {
+	if !strings.HasPrefix(update.Ref, "refs/heads/") && !strings.HasPrefix(update.Ref, "refs/tags/") {
+		return fmt.Errorf("refusing to update %s", update.Ref)
+	}
+	if err := CheckRefName(strings.TrimPrefix(update.Ref, "refs/")); err != nil {
+		return err
+	}
+
+	other, err := checkedOutElsewhere(gtDir, update.Ref, "")
+	if err != nil {
+		return err
+	}
+	if other != "" {
+		return fmt.Errorf("refusing to update checked out branch %s", shortRef(update.Ref))
+	}
+
+	current, err := readRefIn(gtDir, update.Ref)
+	if err != nil {
+		return err
+	}
+	if current != update.OldHash {
+		return fmt.Errorf("%s has moved since it was last read; fetch first", update.Ref)
+	}
+
+	if !objectExistsIn(filepath.Join(gtDir, "objects"), update.NewHash) {
+		return fmt.Errorf("missing object %s", update.NewHash)
+	}
+	return writeRefIn(gtDir, update.Ref, update.NewHash)
+}
+
+// httpTransport talks to a repository served by gt serve.
+type httpTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpTransport) listRefs() (map[string]string, string, error) {
+	resp, err := t.client.Get(t.url + "/info/refs")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, "", err
+	}
+
+	return parseRefAdvertisement(resp.Body)
+}
+
+func (t *httpTransport) fetchObjects(wants []string, haves []string, opts FetchOptions) error {
+	var body bytes.Buffer
+	for _, want := range wants {
+		fmt.Fprintf(&body, "want %s\n", want)
+	}
+	for _, have := range haves {
+		fmt.Fprintf(&body, "have %s\n", have)
+	}
+	if opts.Depth > 0 {
+		fmt.Fprintf(&body, "depth %d\n", opts.Depth)
+	}
+	if opts.BlobNone {
+		body.WriteString("filter blob:none\n")
+	}
+	body.WriteString("done\n")
+
+	resp, err := t.client.Post(t.url+"/upload-pack", "text/plain", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	_, err = readPack(resp.Body, constants.ObjectsDir)
+	return err
+}
+
+func (t *httpTransport) push(update RefUpdate, haves []string) error {
+	objects, err := packObjects(constants.ObjectsDir, []string{update.NewHash}, haves, FetchOptions{})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "%s %s %s\n\n", zeroIfEmpty(update.OldHash), update.NewHash, update.Ref)
+	if err := writePack(&body, constants.ObjectsDir, objects); err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.url+"/receive-pack", "application/octet-stream", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	status, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if reason, ok := strings.CutPrefix(strings.TrimSpace(string(status)), "ng "+update.Ref+" "); ok {
+		return fmt.Errorf("remote rejected %s: %s", shortRef(update.Ref), reason)
+	}
+	return nil
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("remote error: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
+
+// writeRefAdvertisement writes "symref HEAD <ref>" followed by one
+// "<hash> <ref>" line per ref.
+func writeRefAdvertisement(w io.Writer, refs map[string]string, head string) error {
+	if head != "" {
+		if _, err := fmt.Fprintf(w, "symref HEAD %s\n", head); err != nil {
+			return err
+		}
+	}
+	for _, ref := range sortedKeys(refs) {
+		if _, err := fmt.Fprintf(w, "%s %s\n", refs[ref], ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseRefAdvertisement(r io.Reader) (map[string]string, string, error) {
+	refs := make(map[string]string)
+	head := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if target, ok := strings.CutPrefix(line, "symref HEAD "); ok {
+			if err := checkTransferRef(target); err != nil {
+				return nil, "", err
+			}
+			head = target
+			continue
+		}
+		hash, ref, ok := strings.Cut(line, " ")
+		if !ok || !isHex(hash) {
+			return nil, "", fmt.Errorf("bad ref advertisement line: %q", line)
+		}
+		if err := checkTransferRef(ref); err != nil {
+			return nil, "", err
+		}
+		refs[ref] = hash
+	}
+
+	return refs, head, scanner.Err()
+}
+
+// checkTransferRef accepts only valid branch and tag names, the refs
+// another repository may tell us about, so none of them can name a file
+// outside .gt/refs.
+func checkTransferRef(ref string) error {
+	if !strings.HasPrefix(ref, "refs/heads/") && !strings.HasPrefix(ref, "refs/tags/") {
+		return fmt.Errorf("refusing remote ref %s", ref)
+	}
+	return CheckRefName(strings.TrimPrefix(ref, "refs/"))
+}
+
+func zeroIfEmpty(hash string) string {
+	if hash == "" {
+		return ZeroHash
+	}
+	return hash
+}
@@ -8,26 +8,121 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 func ReadObject(hash string) ([]byte, error) {
-	// Construct the full path to the object file
-	objectPath := filepath.Join(constants.ObjectsDir, hash[:2], hash[2:]) // Store objects in subdirectories like Git
+	_, data, err := ReadObjectType(hash)
+	return data, err
+}
 
-	// Read the binary data
-	data, err := os.ReadFile(objectPath)
+// ReadObjectType reads an object and returns the type recorded in its
+// "<type> <size>\0" header together with the content after the header.
+// A partial clone fetches objects it does not have from its promisor remote.
+func ReadObjectType(hash string) (string, []byte, error) {
+	objType, data, err := readStoredObject(hash)
+	if os.IsNotExist(err) {
+		fetched, fetchErr := fetchMissingObject(hash)
+		if fetchErr != nil {
+			return "", nil, fetchErr
+		}
+		if fetched {
+			return readStoredObject(hash)
+		}
+	}
+
+	return objType, data, err
+}
+
+// ReadObjectHeader returns the type and size recorded in the header of a
+// stored object.
+func ReadObjectHeader(hash string) (string, int, error) {
+	raw, err := readRawObject(constants.ObjectsDir, hash)
+	if os.IsNotExist(err) {
+		fetched, fetchErr := fetchMissingObject(hash)
+		if fetchErr != nil {
+			return "", 0, fetchErr
+		}
+		if fetched {
+			raw, err = readRawObject(constants.ObjectsDir, hash)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return "", 0, err
+	}
+
+	header, _, ok := bytes.Cut(raw, []byte{0})
+	if !ok {
+		return "", 0, fmt.Errorf("invalid object format: missing header separator")
+	}
+	objType, sizeText, _ := strings.Cut(string(header), " ")
+	size, err := strconv.Atoi(sizeText)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid object format: bad size %q", sizeText)
+	}
+	return objType, size, nil
+}
+
+// readRawObject returns the stored bytes of an object, header included,
+// from the given objects directory.
+func readRawObject(objectsDir string, hash string) ([]byte, error) {
+	if len(hash) < 4 {
+		return nil, fmt.Errorf("invalid object hash: %q", hash)
 	}
 
+	// Construct the full path to the object file
+	objectPath := repoPath(filepath.Join(objectsDir, hash[:2], hash[2:])) // Store objects in subdirectories like Git
+
+	// Read the binary data
+	return os.ReadFile(objectPath)
+}
+
+// parseObject splits stored object bytes into the type and the content.
+func parseObject(data []byte) (string, []byte, error) {
 	nullIndex := bytes.IndexByte(data, 0)
 	if nullIndex == -1 {
-		return nil, fmt.Errorf("invalid object format: missing header separator")
+		return "", nil, fmt.Errorf("invalid object format: missing header separator")
+	}
+
+	objType, _, _ := strings.Cut(string(data[:nullIndex]), " ")
+	switch objType {
+	case "blob", "tree", "commit", "tag":
+	default:
+		return "", nil, fmt.Errorf("invalid object format: unknown type %q", objType)
 	}
 
 	// Return the content after the null byte
-	return data[nullIndex+1:], nil
+	return objType, data[nullIndex+1:], nil
+}
 
+// ObjectExists reports whether an object with the given hash is stored.
+func ObjectExists(hash string) bool {
+	return objectExistsIn(constants.ObjectsDir, hash)
+}
+
+func objectExistsIn(objectsDir string, hash string) bool {
+	if len(hash) < 4 {
+		return false
+	}
+	_, err := os.Stat(repoPath(filepath.Join(objectsDir, hash[:2], hash[2:])))
+	return err == nil
+}
+
+// writeObjectFile stores already encoded object content under its hash. The
+// object only appears once it is completely on disk, so a crash never leaves
+// a truncated object behind; an object that exists is left alone.
+func writeObjectFile(hash string, content []byte, objectsDir string) error {
+	objectPath := repoPath(filepath.Join(objectsDir, hash[:2], hash[2:]))
+	if _, err := os.Stat(objectPath); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(objectPath, content, 0644)
 }
 
 func ReadStash(hash string) ([]byte, error) {
@@ -69,3 +164,21 @@ func HasUncommitedChanges() {
 	// fileTree := RootDir()
 
 }
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
+
+// headName describes HEAD for reflog messages: the branch name, or the commit
+// hash when detached.
+func headName() (string, error) {
+	symref, hash, err := ReadHead()
+	if err != nil {
+		return "", err
+	}
+	if symref != "" {
+		return strings.TrimPrefix(symref, "refs/heads/"), nil
+	}
+	return hash, nil
+}
@@ -0,0 +1,351 @@
+package vcs
+
+import (
+	"GoTrack/constants"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A linked worktree is a second working directory of a repository. Its .gt
+// is a file holding "gtdir: <path>", which points at
+// .gt/worktrees/<name> in the main repository. That directory keeps the
+// worktree's own HEAD, index, HEAD reflog, sparse patterns and in-progress
+// operations such as a rebase or bisect, while objects, refs and config are
+// shared with the main repository.
+
+// Worktree is one working directory of the repository.
+type Worktree struct {
+	Path  string // Absolute path of the working directory
+	Head  string // Ref HEAD points at, "" when detached
+	Hash  string // Commit HEAD resolves to
+	Name  string // Name under .gt/worktrees, "" for the main worktree
+	Admin string // The worktree's own .gt directory
+}
+
+// perWorktree lists what each worktree keeps for itself below .gt.
+var perWorktree = []string{"HEAD", "index", "logs/HEAD", "sparse-checkout", "sequencer", "rebase-merge", "bisect"}
+
+// repoPath maps a path inside a .gt directory, such as constants.ObjectsDir
+// or "/repo/.gt/refs/heads/main", to where it is stored. Only paths through
+// the .gt file of a linked worktree change; everything else is returned as
+// is.
+func repoPath(path string) string {
+	root, rest, ok := splitGTPath(path)
+	if !ok {
+		return path
+	}
+
+	admin, ok := readGTFile(filepath.Join(root, constants.GTDir))
+	if !ok {
+		return path
+	}
+
+	for _, name := range perWorktree {
+		if rest == name || strings.HasPrefix(rest, name+"/") {
+			return filepath.Join(admin, rest)
+		}
+	}
+	return filepath.Join(commonDir(admin), rest)
+}
+
+// splitGTPath splits path into the directory holding .gt and the part
+// below .gt.
+func splitGTPath(path string) (string, string, bool) {
+	slashed := filepath.ToSlash(path)
+	gtDir := constants.GTDir
+
+	switch {
+	case slashed == gtDir:
+		return ".", "", true
+	case strings.HasPrefix(slashed, gtDir+"/"):
+		return ".", slashed[len(gtDir)+1:], true
+	case strings.HasSuffix(slashed, "/"+gtDir):
+		return filepath.FromSlash(slashed[:len(slashed)-len(gtDir)-1]), "", true
+	}
+
+	if i := strings.Index(slashed, "/"+gtDir+"/"); i >= 0 {
+		return filepath.FromSlash(slashed[:i]), slashed[i+len(gtDir)+2:], true
+	}
+	return "", "", false
+}
+
+// readGTFile returns the directory a linked worktree's .gt file points at.
+// It reports false when gtPath is a directory or does not exist.
+func readGTFile(gtPath string) (string, bool) {
+	info, err := os.Lstat(gtPath)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	data, err := os.ReadFile(gtPath)
+	if err != nil {
+		return "", false
+	}
+	admin, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gtdir: ")
+	return admin, ok
+}
+
+// commonDir returns the main .gt directory of a worktree admin directory,
+// which lives at <main>/.gt/worktrees/<name>.
+func commonDir(admin string) string {
+	return filepath.Dir(filepath.Dir(admin))
+}
+
+// ListWorktrees returns the main worktree followed by the linked ones.
+func ListWorktrees() ([]Worktree, error) {
+	return listWorktreesIn(repoPath(constants.GTDir))
+}
+
+func listWorktreesIn(gtDir string) ([]Worktree, error) {
+	gtDir, err := filepath.Abs(gtDir)
+	if err != nil {
+		return nil, err
+	}
+
+	main := Worktree{Path: filepath.Dir(gtDir), Admin: gtDir}
+	if main.Head, main.Hash, err = readHeadIn(gtDir); err != nil {
+		return nil, err
+	}
+	worktrees := []Worktree{main}
+
+	entries, err := os.ReadDir(filepath.Join(gtDir, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		admin := filepath.Join(gtDir, "worktrees", entry.Name())
+		data, err := os.ReadFile(filepath.Join(admin, "gtdir"))
+		if err != nil {
+			return nil, err
+		}
+
+		worktree := Worktree{Path: filepath.Dir(strings.TrimSpace(string(data))), Name: entry.Name(), Admin: admin}
+		if worktree.Head, worktree.Hash, err = readWorktreeHead(gtDir, admin); err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, worktree)
+	}
+
+	return worktrees, nil
+}
+
+// readWorktreeHead reads the HEAD of a linked worktree, whose refs live in
+// the main .gt directory.
+func readWorktreeHead(gtDir string, admin string) (string, string, error) {
+	data, err := os.ReadFile(filepath.Join(admin, "HEAD"))
+	if err != nil {
+		return "", "", err
+	}
+
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		hash, err := readRefIn(gtDir, ref)
+		return ref, hash, err
+	}
+	return "", head, nil
+}
+
+// checkedOutElsewhere returns the path of a worktree of the repository in
+// gtDir that has ref checked out, or "". The worktree whose admin directory
+// is exclude does not count.
+func checkedOutElsewhere(gtDir string, ref string, exclude string) (string, error) {
+	worktrees, err := listWorktreesIn(gtDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, worktree := range worktrees {
+		if worktree.Head == ref && worktree.Admin != exclude {
+			return worktree.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// currentAdmin returns the absolute .gt directory of the current worktree:
+// the main .gt, or .gt/worktrees/<name> for a linked worktree.
+func currentAdmin() (string, error) {
+	return filepath.Abs(filepath.Dir(repoPath(filepath.Join(constants.GTDir, "HEAD"))))
+}
+
+// AddWorktree creates a linked worktree at path with rev checked out. A
+// branch name attaches its HEAD to the branch, which must not be checked out
+// in any other worktree; anything else detaches it.
+func AddWorktree(path string, rev string) (Worktree, error) {
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	head := hash
+	if BranchExists(rev) {
+		head = "ref: refs/heads/" + rev
+		other, err := checkedOutElsewhere(repoPath(constants.GTDir), "refs/heads/"+rev, "")
+		if err != nil {
+			return Worktree{}, err
+		}
+		if other != "" {
+			return Worktree{}, fmt.Errorf("'%s' is already checked out at '%s'", rev, other)
+		}
+	}
+
+	if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+		return Worktree{}, fmt.Errorf("'%s' already exists and is not empty", path)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return Worktree{}, err
+	}
+
+	common, err := filepath.Abs(repoPath(constants.GTDir))
+	if err != nil {
+		return Worktree{}, err
+	}
+	name, err := worktreeName(common, filepath.Base(path))
+	if err != nil {
+		return Worktree{}, err
+	}
+	admin := filepath.Join(common, "worktrees", name)
+
+	if err := os.MkdirAll(admin, 0755); err != nil {
+		return Worktree{}, err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		os.RemoveAll(admin)
+		return Worktree{}, err
+	}
+
+	worktree := Worktree{Path: path, Name: name, Admin: admin, Hash: hash}
+	if strings.HasPrefix(head, "ref: ") {
+		worktree.Head = strings.TrimPrefix(head, "ref: ")
+	}
+
+	if err := populateWorktree(worktree, head); err != nil {
+		os.RemoveAll(admin)
+		os.RemoveAll(path)
+		return Worktree{}, err
+	}
+	return worktree, nil
+}
+
+// worktreeName picks an unused name under .gt/worktrees based on base.
+func worktreeName(common string, base string) (string, error) {
+	base = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\n~^:?*[\\", r) {
+			return '-'
+		}
+		return r
+	}, base)
+
+	name := base
+	for i := 1; ; i++ {
+		_, err := os.Stat(filepath.Join(common, "worktrees", name))
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// populateWorktree writes the admin files and the .gt pointer of a new
+// worktree, then checks out its files from inside it.
+func populateWorktree(worktree Worktree, head string) error {
+	gtFile := filepath.Join(worktree.Path, constants.GTDir)
+	if err := os.WriteFile(filepath.Join(worktree.Admin, "gtdir"), []byte(gtFile+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(worktree.Admin, "HEAD"), []byte(head+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(gtFile, []byte("gtdir: "+worktree.Admin+"\n"), 0644); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(worktree.Path); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	files, err := FlattenCommit(worktree.Hash)
+	if err != nil {
+		return err
+	}
+	if err := UpdateWorkingTree(map[string]IndexEntry{}, files); err != nil {
+		return err
+	}
+	if err := WriteIndex(files); err != nil {
+		return err
+	}
+	return appendReflog("HEAD", "", worktree.Hash, "worktree: add "+worktree.Name)
+}
+
+// RemoveWorktree deletes a linked worktree and its admin directory. Unless
+// force is set, a worktree with uncommitted changes is kept.
+func RemoveWorktree(path string, force bool) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	worktrees, err := ListWorktrees()
+	if err != nil {
+		return err
+	}
+
+	for i, worktree := range worktrees {
+		if worktree.Path != path {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("'%s' is the main worktree", path)
+		}
+
+		if !force {
+			clean, err := worktreeIsClean(worktree)
+			if err != nil {
+				return err
+			}
+			if !clean {
+				return fmt.Errorf("'%s' has uncommitted changes; use --force to remove it anyway", path)
+			}
+		}
+
+		if wd, err := os.Getwd(); err == nil && (wd == path || strings.HasPrefix(wd, path+string(filepath.Separator))) {
+			return fmt.Errorf("cannot remove the worktree you are in")
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		return os.RemoveAll(worktree.Admin)
+	}
+
+	return fmt.Errorf("'%s' is not a worktree", path)
+}
+
+func worktreeIsClean(worktree Worktree) (bool, error) {
+	if _, err := os.Stat(worktree.Path); os.IsNotExist(err) {
+		return true, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chdir(worktree.Path); err != nil {
+		return false, err
+	}
+	defer os.Chdir(wd)
+
+	status, err := GetStatus()
+	if err != nil {
+		return false, err
+	}
+	return len(status.Staged) == 0 && len(status.Unstaged) == 0 && len(status.Untracked) == 0, nil
+}